@@ -0,0 +1,94 @@
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// tempSeq makes the names of temp files unique within the process.
+var tempSeq uint32
+
+// WriteAtomic writes content to path so that path holds either its old or its
+// new content, even if the process crashes. The content is written to a temp
+// file in the same directory, synced, renamed over path, and the directory is
+// synced. An existing file keeps its permissions and, where possible, its owner.
+// If path is a symlink, its target is replaced.
+func WriteAtomic[T string | []byte](path string, content T) (err error) {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	info, statErr := os.Stat(path)
+	if statErr == nil && !info.Mode().IsRegular() {
+		return &os.PathError{Op: "write", Path: path, Err: errors.New("not a regular file")}
+	}
+
+	perm := fs.FileMode(0o666)
+	if statErr == nil {
+		perm = info.Mode().Perm()
+	}
+	dir := filepath.Dir(path)
+	file, err := createTemp(dir, filepath.Base(path), perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+		}
+	}()
+	// The temp file is created with at most the permissions of path, and
+	// gets them exactly before writing, so that the content of a private
+	// file is never readable by others.
+	if statErr == nil {
+		if err = file.Chmod(info.Mode().Perm()); err != nil {
+			return err
+		}
+		chown(file, info)
+	}
+	if _, err = file.Write([]byte(content)); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(file.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// maxTempName is the longest file name kept in the name of a temp file.
+const maxTempName = 200
+
+// createTemp creates a new hidden temp file for name in dir with perm
+// before umask. Unlike os.CreateTemp, new files thus get the mode of the
+// files of Write.
+func createTemp(dir, name string, perm fs.FileMode) (*os.File, error) {
+	// Long names are shortened to keep within the usual 255 byte limit.
+	if len(name) > maxTempName {
+		name = name[:maxTempName]
+		for !utf8.ValidString(name) {
+			name = name[:len(name)-1]
+		}
+	}
+	for i := 0; ; i++ {
+		seq := atomic.AddUint32(&tempSeq, 1)
+		suffix := strconv.FormatInt(time.Now().UnixNano()+int64(seq), 36)
+		path := filepath.Join(dir, "."+name+".tmp-"+suffix)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) && i < 100 {
+			continue
+		}
+		return file, err
+	}
+}
@@ -0,0 +1,112 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.conf")
+	if err := os.WriteFile(existing, []byte("old content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"new", filepath.Join(dir, "new.conf"), "hello"},
+		{"existing", existing, "new"},
+		{"empty", filepath.Join(dir, "empty.conf"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := WriteAtomic(tt.path, tt.want); err != nil {
+				t.Fatalf("WriteAtomic(): name %v , got %v, want %v", tt.name, err, nil)
+			}
+			if got, err := Read(tt.path); got != tt.want || err != nil {
+				t.Errorf("WriteAtomic(): name %v , got %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	if err := WriteAtomic(existing, []byte("bytes")); err != nil {
+		t.Fatalf("WriteAtomic(): %v", err)
+	}
+	if got, _ := Read(existing); got != "bytes" {
+		t.Errorf("WriteAtomic(): got %v, want %v", got, "bytes")
+	}
+	if runtime.GOOS != "windows" {
+		if info, _ := os.Stat(existing); info.Mode().Perm() != 0o600 {
+			t.Errorf("WriteAtomic(): got mode %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("WriteAtomic(): got %v files, want 3 without temp files", len(entries))
+	}
+}
+
+func TestWriteAtomicSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.conf")
+	link := filepath.Join(dir, "link.conf")
+	if err := os.WriteFile(target, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("target.conf", link); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteAtomic(link, "new"); err != nil {
+		t.Fatalf("WriteAtomic(): %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("WriteAtomic(): replaced the symlink")
+	}
+	if got, _ := Read(target); got != "new" {
+		t.Errorf("WriteAtomic(): got %v, want %v", got, "new")
+	}
+}
+
+func TestWriteAtomicErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"missing dir", filepath.Join(dir, "missing", "app.conf")},
+		{"dir", dir},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := WriteAtomic(tt.path, "x"); err == nil {
+				t.Errorf("WriteAtomic(): name %v , got %v, want error", tt.name, err)
+			}
+		})
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("WriteAtomic(): left %v behind", entries[0].Name())
+	}
+}
+
+func TestCreateTemp(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows has no permission bits")
+	}
+	file, err := createTemp(t.TempDir(), "secret.conf", 0o600)
+	if err != nil {
+		t.Fatalf("createTemp(): %v", err)
+	}
+	defer file.Close()
+	if info, _ := file.Stat(); info.Mode().Perm() != 0o600 || !strings.HasPrefix(info.Name(), ".secret.conf.tmp-") {
+		t.Errorf("createTemp(): got %v %v, want a private temp file", info.Name(), info.Mode().Perm())
+	}
+}
@@ -0,0 +1,534 @@
+package ufile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Conflict is what a copy does when a destination file exists.
+type Conflict int
+
+const (
+	// ConflictOverwrite replaces the destination file.
+	ConflictOverwrite Conflict = iota
+	// ConflictSkip keeps the destination file and skips the source file.
+	ConflictSkip
+	// ConflictRename copies to the first free name like "name (1).ext".
+	ConflictRename
+)
+
+// CopyOptions configures CopyDirWithOptions and CopyFileWithOptions.
+type CopyOptions struct {
+	// KeepSymlinks copies symbolic links as links instead of copying the
+	// files and directories they point to.
+	KeepSymlinks bool
+	// PreserveMode, PreserveTimes and PreserveOwner copy the permissions,
+	// modification times and owners of files and directories.
+	PreserveMode, PreserveTimes, PreserveOwner bool
+	// Conflict is what to do with existing destination files.
+	Conflict Conflict
+	// Filter selects the files and directories to copy by their
+	// slash-separated path relative to the source directory. Returning
+	// false for a directory skips its subtree. (*Filter).Match can be used.
+	// The directories are walked concurrently, but the calls are serialized.
+	Filter func(rel string, entry fs.DirEntry) bool
+	// DryRun reports what would be copied without writing anything.
+	DryRun bool
+	// OnCopy is called with each file copied, or that would be copied,
+	// and its destination after renaming.
+	OnCopy func(src, dst string)
+	// Progress is called with the progress of the copy every
+	// ProgressInterval, 100ms by default, and when the copy ends.
+	Progress         func(CopyProgress)
+	ProgressInterval time.Duration
+}
+
+// CopyProgress is the progress of a copy. Files skipped or failing
+// count as done.
+type CopyProgress struct {
+	// File is the source file being copied.
+	File              string
+	Files, TotalFiles int
+	Bytes, TotalBytes int64
+	Elapsed           time.Duration
+}
+
+// Rate returns the bytes copied per second.
+func (p CopyProgress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Bytes) / p.Elapsed.Seconds()
+}
+
+// String returns the progress for humans, like
+// "3/10 files, 1.00 MB/4.00 MB (25%), 512.00 KB/s, dir/file.bin".
+func (p CopyProgress) String() string {
+	percent := 100
+	if p.TotalBytes > 0 {
+		percent = int(p.Bytes * 100 / p.TotalBytes)
+	} else if p.TotalFiles > 0 {
+		percent = p.Files * 100 / p.TotalFiles
+	}
+	s := fmt.Sprintf("%d/%d files, %s/%s (%d%%), %s/s", p.Files, p.TotalFiles,
+		FormatSize(p.Bytes), FormatSize(p.TotalBytes), percent, FormatSize(int64(p.Rate())))
+	if p.File != "" {
+		s += ", " + p.File
+	}
+	return s
+}
+
+// maxRenames is the number of names ConflictRename tries.
+const maxRenames = 1000
+
+// copyModeBits are the mode bits preserved with PreserveMode.
+const copyModeBits = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+// CopyDirWithOptions copies a directory from src to dst as configured by
+// opts. Existing directories are merged. An error on src itself is returned
+// as is. A file that fails to copy does not stop the copy, and
+// CopyDirWithOptions then returns Errors holding the error of every file.
+func CopyDirWithOptions(src, dst string, opts CopyOptions) error {
+	return CopyDirContext(context.Background(), src, dst, opts)
+}
+
+// CopyDirContext is CopyDirWithOptions stopping when ctx is done. The partial
+// copy of the file being copied is then removed, leaving an existing
+// destination file as it was, and the error of ctx is among the Errors.
+func CopyDirContext(ctx context.Context, src, dst string, opts CopyOptions) error {
+	c := newCopier(ctx, opts)
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &fs.PathError{Op: "copy", Path: src, Err: errors.New("not a directory")}
+	}
+	items := c.collect(src)
+	for _, item := range items {
+		if !item.info.IsDir() {
+			c.progress.TotalFiles++
+			if item.info.Mode().IsRegular() {
+				c.progress.TotalBytes += item.info.Size()
+			}
+		}
+	}
+	defer c.report(true)
+	if !opts.DryRun {
+		if err := os.MkdirAll(dst, 0o755); err != nil {
+			return append(c.errs, err)
+		}
+	}
+
+	// failed holds the directories that could not be created.
+	failed := map[string]bool{}
+	dirs := []copyItem{{src: src, info: info}}
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return c.cancelled()
+		}
+		if failed[path.Dir(item.rel)] {
+			failed[item.rel] = true
+			continue
+		}
+		target := filepath.Join(dst, filepath.FromSlash(item.rel))
+		if item.info.IsDir() {
+			if err := c.mkdir(target); err != nil {
+				c.errs = append(c.errs, err)
+				failed[item.rel] = true
+				continue
+			}
+			dirs = append(dirs, item)
+			continue
+		}
+		if err := c.copyEntry(item.src, target, item.info); err != nil {
+			c.errs = append(c.errs, err)
+		}
+	}
+	// Directories get their attributes last, as adding files changes their
+	// times and their permissions may not allow adding files.
+	if !opts.DryRun {
+		for i := len(dirs) - 1; i >= 0; i-- {
+			target := filepath.Join(dst, filepath.FromSlash(dirs[i].rel))
+			if err := c.setAttrs(target, dirs[i].info); err != nil {
+				c.errs = append(c.errs, err)
+			}
+		}
+	}
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs
+}
+
+// CopyFileWithOptions copies a file from src to dst as configured by opts,
+// creating the directory of dst if needed. The Filter of opts is not used.
+func CopyFileWithOptions(src, dst string, opts CopyOptions) error {
+	return CopyFileContext(context.Background(), src, dst, opts)
+}
+
+// CopyFileContext is CopyFileWithOptions stopping when ctx is done,
+// removing the partial copy and leaving an existing dst as it was.
+func CopyFileContext(ctx context.Context, src, dst string, opts CopyOptions) error {
+	c := newCopier(ctx, opts)
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 && !opts.KeepSymlinks {
+		if info, err = os.Stat(src); err != nil {
+			return err
+		}
+	}
+	if info.IsDir() {
+		return &fs.PathError{Op: "copy", Path: src, Err: errors.New("is a directory")}
+	}
+	if !opts.DryRun {
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+	}
+	c.progress.TotalFiles = 1
+	if info.Mode().IsRegular() {
+		c.progress.TotalBytes = info.Size()
+	}
+	defer c.report(true)
+	return c.copyEntry(src, dst, info)
+}
+
+// cancelled returns the errors of a copy stopped as the context is done.
+func (c *copier) cancelled() error {
+	if err := c.ctx.Err(); !c.errs.Is(err) {
+		c.errs = append(c.errs, err)
+	}
+	return c.errs
+}
+
+// copyItem is a file or directory to copy.
+type copyItem struct {
+	src string
+	// rel is the slash-separated path relative to the source directory.
+	rel string
+	// info is of the link itself with KeepSymlinks, else of its target.
+	info fs.FileInfo
+}
+
+// copier holds the state of a copy.
+type copier struct {
+	ctx  context.Context
+	opts CopyOptions
+	errs Errors
+	mu   sync.Mutex
+
+	progress   CopyProgress
+	start      time.Time
+	lastReport time.Time
+}
+
+func newCopier(ctx context.Context, opts CopyOptions) *copier {
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = 100 * time.Millisecond
+	}
+	now := time.Now()
+	return &copier{ctx: ctx, opts: opts, start: now, lastReport: now}
+}
+
+// report calls the Progress of the options if the interval has passed or force is set.
+func (c *copier) report(force bool) {
+	if c.opts.Progress == nil {
+		return
+	}
+	now := time.Now()
+	if !force && now.Sub(c.lastReport) < c.opts.ProgressInterval {
+		return
+	}
+	c.lastReport = now
+	c.progress.Elapsed = now.Sub(c.start)
+	c.opts.Progress(c.progress)
+}
+
+// progressReader reads a file being copied, counting the bytes done and
+// stopping when the context is done.
+type progressReader struct {
+	c *copier
+	r io.Reader
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	if err := r.c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	r.c.progress.Bytes += int64(n)
+	r.c.report(false)
+	return n, err
+}
+
+func (c *copier) addError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs Errors
+	if errors.As(err, &errs) {
+		c.errs = append(c.errs, errs...)
+		return
+	}
+	c.errs = append(c.errs, err)
+}
+
+// collect returns the entries below src to copy, sorted so that
+// directories come before their contents.
+func (c *copier) collect(src string) []copyItem {
+	// copyRoot is a directory walked for the copy, with the real paths of
+	// the directories followed to reach it to detect symlink cycles.
+	type copyRoot struct {
+		path, rel string
+		chain     []string
+	}
+	real, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		c.addError(err)
+		return nil
+	}
+	var items []copyItem
+	roots := []copyRoot{{path: real, chain: []string{real}}}
+	for len(roots) > 0 {
+		root := roots[0]
+		roots = roots[1:]
+		err := Walk(c.ctx, root.path, WalkOptions{}, func(fullpath string, entry fs.DirEntry) error {
+			if fullpath == root.path {
+				return nil
+			}
+			rel, err := filepath.Rel(root.path, fullpath)
+			if err != nil {
+				return err
+			}
+			rel = path.Join(root.rel, filepath.ToSlash(rel))
+			info, err := entry.Info()
+			if err == nil && info.Mode()&fs.ModeSymlink != 0 && !c.opts.KeepSymlinks {
+				info, err = os.Stat(fullpath)
+			}
+			if err != nil {
+				c.addError(err)
+				return nil
+			}
+			if c.opts.Filter != nil && !c.filter(rel, info) {
+				if entry.IsDir() {
+					return SkipDir
+				}
+				return nil
+			}
+
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			items = append(items, copyItem{src: fullpath, rel: rel, info: info})
+			if info.IsDir() && !entry.IsDir() {
+				// A followed link to a directory is walked after this root.
+				target, err := filepath.EvalSymlinks(fullpath)
+				if err != nil {
+					c.errs = append(c.errs, err)
+				} else if isCycle(target, root.chain) {
+					c.errs = append(c.errs, &fs.PathError{Op: "copy", Path: fullpath, Err: errors.New("symlink cycle")})
+				} else {
+					chain := append(root.chain[:len(root.chain):len(root.chain)], target)
+					roots = append(roots, copyRoot{path: target, rel: rel, chain: chain})
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			c.addError(err)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].rel < items[j].rel
+	})
+	return items
+}
+
+// filter calls the Filter of the options one at a time.
+func (c *copier) filter(rel string, info fs.FileInfo) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.opts.Filter(rel, fs.FileInfoToDirEntry(info))
+}
+
+// isCycle reports whether the directory target is one of the directories
+// in chain or one of their parents.
+func isCycle(target string, chain []string) bool {
+	for _, dir := range chain {
+		if dir == target || strings.HasPrefix(dir, target+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// mkdir creates the directory name if it does not exist.
+func (c *copier) mkdir(name string) error {
+	if c.opts.DryRun {
+		return nil
+	}
+	err := os.Mkdir(name, 0o755)
+	if errors.Is(err, fs.ErrExist) && IsDir(name) {
+		return nil
+	}
+	return err
+}
+
+// copyEntry copies the file or symbolic link src with info to dst,
+// updating the progress.
+func (c *copier) copyEntry(src, dst string, info fs.FileInfo) error {
+	bytes := c.progress.Bytes
+	c.progress.File = src
+	defer func() {
+		c.progress.Files++
+		if info.Mode().IsRegular() {
+			c.progress.Bytes = bytes + info.Size()
+		}
+		c.report(false)
+	}()
+	isLink := info.Mode()&fs.ModeSymlink != 0
+	if !isLink && !info.Mode().IsRegular() {
+		return &fs.PathError{Op: "copy", Path: src, Err: errors.New("not a regular file")}
+	}
+	dst, ok, err := c.destination(dst, isLink)
+	if err != nil || !ok {
+		return err
+	}
+	if c.opts.OnCopy != nil {
+		c.opts.OnCopy(src, dst)
+	}
+	if c.opts.DryRun {
+		return nil
+	}
+	if isLink {
+		link, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(link, dst); err != nil {
+			return err
+		}
+	} else if err := c.copyContents(src, dst); err != nil {
+		return err
+	}
+	return c.setAttrs(dst, info)
+}
+
+// destination returns the path to copy to for dst, or false to skip it,
+// following the conflict policy. An existing link to overwrite is removed,
+// so that a file is not written through it.
+func (c *copier) destination(dst string, isLink bool) (string, bool, error) {
+	existing, err := os.Lstat(dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		return dst, true, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	switch c.opts.Conflict {
+	case ConflictSkip:
+		return "", false, nil
+	case ConflictRename:
+		// The extension of a dotfile like ".env" is part of its name.
+		ext := filepath.Ext(filepath.Base(dst))
+		if ext == filepath.Base(dst) {
+			ext = ""
+		}
+		base := strings.TrimSuffix(dst, ext)
+		for i := 1; i <= maxRenames; i++ {
+			name := base + " (" + strconv.Itoa(i) + ")" + ext
+			_, err := os.Lstat(name)
+			if errors.Is(err, fs.ErrNotExist) {
+				return name, true, nil
+			}
+			if err != nil {
+				return "", false, err
+			}
+		}
+		return "", false, &fs.PathError{Op: "copy", Path: dst, Err: errors.New("no free name to rename to")}
+	}
+	if (isLink || existing.Mode()&fs.ModeSymlink != 0) && !c.opts.DryRun {
+		if err := os.Remove(dst); err != nil {
+			return "", false, err
+		}
+	}
+	return dst, true, nil
+}
+
+// copyContents copies the contents of the file src to a temp file renamed
+// to dst on success, so that a failed or cancelled copy leaves an existing
+// dst as it was. An existing dst keeps its permissions and, where possible,
+// its owner, as when it is written in place.
+func (c *copier) copyContents(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	existing, statErr := os.Stat(dst)
+	replace := statErr == nil && existing.Mode().IsRegular()
+	perm := fs.FileMode(0o666)
+	if replace {
+		perm = existing.Mode().Perm()
+	}
+	out, err := createTemp(filepath.Dir(dst), filepath.Base(dst), perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(out.Name())
+		}
+	}()
+	if replace {
+		if err = out.Chmod(perm); err != nil {
+			return err
+		}
+		chown(out, existing)
+	}
+	if _, err = io.Copy(out, &progressReader{c: c, r: in}); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), dst)
+}
+
+// setAttrs gives name the attributes of info that are preserved.
+func (c *copier) setAttrs(name string, info fs.FileInfo) error {
+	// Changing the owner may clear the setuid and setgid bits,
+	// so it comes before the mode.
+	if c.opts.PreserveOwner {
+		if err := lchown(name, info); err != nil {
+			return err
+		}
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return nil
+	}
+	if c.opts.PreserveMode {
+		if err := os.Chmod(name, info.Mode()&copyModeBits); err != nil {
+			return err
+		}
+	}
+	if c.opts.PreserveTimes {
+		if err := os.Chtimes(name, info.ModTime(), info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
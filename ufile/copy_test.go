@@ -0,0 +1,327 @@
+package ufile
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// treeFiles returns the sorted slash paths of the files below dir with their
+// contents, and the targets of symbolic links prefixed with "->".
+func treeFiles(t *testing.T, dir string) string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		if d.Type()&fs.ModeSymlink != 0 {
+			link, _ := os.Readlink(p)
+			files = append(files, rel+"->"+filepath.ToSlash(link))
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		files = append(files, rel+"="+string(b))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return strings.Join(files, " ")
+}
+
+func TestCopyDirWithOptions(t *testing.T) {
+	src := t.TempDir()
+	makeTree(t, src, "a.txt", "b/c.txt", "b/d.log", "empty/")
+
+	dst := filepath.Join(t.TempDir(), "copy")
+	if err := CopyDirWithOptions(src+string(filepath.Separator), dst, CopyOptions{}); err != nil {
+		t.Fatalf("CopyDirWithOptions(): %v", err)
+	}
+	if got, want := treeFiles(t, dst), "a.txt=a.txt b/c.txt=b/c.txt b/d.log=b/d.log"; got != want {
+		t.Errorf("CopyDirWithOptions(): got %v, want %v", got, want)
+	}
+	if !IsDir(filepath.Join(dst, "empty")) {
+		t.Errorf("CopyDirWithOptions(): empty directory not copied")
+	}
+
+	// The filter keeps state without locking, as its calls are serialized.
+	var copied []string
+	seen := map[string]bool{}
+	dst = filepath.Join(t.TempDir(), "dry")
+	err := CopyDirWithOptions(src, dst, CopyOptions{
+		DryRun: true,
+		Filter: func(rel string, entry fs.DirEntry) bool {
+			seen[rel] = true
+			return !strings.HasSuffix(rel, ".log")
+		},
+		OnCopy: func(src, dst string) { copied = append(copied, filepath.Base(dst)) },
+	})
+	if err != nil || strings.Join(copied, " ") != "a.txt c.txt" || IsExist(dst) || len(seen) != 5 {
+		t.Errorf("CopyDirWithOptions(): dry run got %v %v", copied, err)
+	}
+}
+
+func TestCopyConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		conflict Conflict
+		want     string
+	}{
+		{"overwrite", ConflictOverwrite, ".env=.env a (1).txt=old a.txt=a.txt b.txt=b.txt"},
+		{"skip", ConflictSkip, ".env=old a (1).txt=old a.txt=old b.txt=b.txt"},
+		{"rename", ConflictRename, ".env (1)=.env .env=old a (1).txt=old a (2).txt=a.txt a.txt=old b.txt=b.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, dst := t.TempDir(), t.TempDir()
+			makeTree(t, src, "a.txt", "b.txt", ".env")
+			for _, name := range []string{"a.txt", "a (1).txt", ".env"} {
+				if err := os.WriteFile(filepath.Join(dst, name), []byte("old"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := CopyDirWithOptions(src, dst, CopyOptions{Conflict: tt.conflict}); err != nil {
+				t.Fatalf("CopyDirWithOptions(): %v", err)
+			}
+			if got := treeFiles(t, dst); got != tt.want {
+				t.Errorf("CopyDirWithOptions(): name %v , got %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyOverwrite(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	long := strings.Repeat("x", 251) + ".txt"
+	makeTree(t, src, "a.txt", long)
+	makeTree(t, dst, "a.txt", long)
+	if err := os.Chmod(filepath.Join(dst, "a.txt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CopyDirWithOptions(src, dst, CopyOptions{}); err != nil {
+		t.Fatalf("CopyDirWithOptions(): %v", err)
+	}
+	if got := treeFiles(t, dst); got != "a.txt=new "+long+"="+long {
+		t.Errorf("CopyDirWithOptions(): got %v, want the files replaced without temp files", got)
+	}
+	if info, _ := os.Stat(filepath.Join(dst, "a.txt")); runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf("CopyDirWithOptions(): got mode %v, want %v kept", info.Mode().Perm(), fs.FileMode(0o600))
+	}
+
+	// No " (n)" name fits beside a 255 byte name, which is an error.
+	err := CopyDirWithOptions(src, dst, CopyOptions{Conflict: ConflictRename, Filter: func(rel string, entry fs.DirEntry) bool {
+		return rel == long
+	}})
+	if err == nil || !strings.Contains(err.Error(), " (1).txt") {
+		t.Errorf("CopyDirWithOptions(): got %v, want a name too long", err)
+	}
+}
+
+func TestCopyPreserve(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	makeTree(t, src, "bin/run.sh")
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, name := range []string{"bin/run.sh", "bin"} {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.Chmod(p, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := CopyOptions{PreserveMode: true, PreserveTimes: true, PreserveOwner: os.Geteuid() == 0}
+	if err := CopyDirWithOptions(src, dst, opts); err != nil {
+		t.Fatalf("CopyDirWithOptions(): %v", err)
+	}
+	for _, name := range []string{"bin/run.sh", "bin"} {
+		info, err := os.Stat(filepath.Join(dst, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("CopyDirWithOptions(): %v got mtime %v, want %v", name, info.ModTime(), mtime)
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm() != 0o750 {
+			t.Errorf("CopyDirWithOptions(): %v got mode %v, want %v", name, info.Mode().Perm(), fs.FileMode(0o750))
+		}
+	}
+}
+
+func TestCopySymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	src := t.TempDir()
+	makeTree(t, src, "a.txt", "dir/b.txt")
+	for link, target := range map[string]string{"link.txt": "a.txt", "linkdir": "dir", "dir/loop": ".."} {
+		if err := os.Symlink(target, filepath.Join(src, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dst := t.TempDir()
+	if err := CopyDirWithOptions(src, dst, CopyOptions{KeepSymlinks: true}); err != nil {
+		t.Fatalf("CopyDirWithOptions(): %v", err)
+	}
+	if got, want := treeFiles(t, dst), "a.txt=a.txt dir/b.txt=dir/b.txt dir/loop->.. link.txt->a.txt linkdir->dir"; got != want {
+		t.Errorf("CopyDirWithOptions(): keep got %v, want %v", got, want)
+	}
+
+	// Following links copies what they point to, and the link back to the
+	// source directory is a cycle reported after copying the rest.
+	dst = t.TempDir()
+	err := CopyDirWithOptions(src, dst, CopyOptions{})
+	if got, want := treeFiles(t, dst), "a.txt=a.txt dir/b.txt=dir/b.txt link.txt=a.txt linkdir/b.txt=dir/b.txt"; got != want {
+		t.Errorf("CopyDirWithOptions(): follow got %v, want %v", got, want)
+	}
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 2 || !strings.Contains(err.Error(), "symlink cycle") {
+		t.Errorf("CopyDirWithOptions(): got %v, want two symlink cycles", err)
+	}
+
+	dst = filepath.Join(t.TempDir(), "sub", "link.txt")
+	if err := CopyFileWithOptions(filepath.Join(src, "link.txt"), dst, CopyOptions{KeepSymlinks: true}); err != nil {
+		t.Fatalf("CopyFileWithOptions(): %v", err)
+	}
+	if link, err := os.Readlink(dst); link != "a.txt" || err != nil {
+		t.Errorf("CopyFileWithOptions(): got link %v %v, want a.txt", link, err)
+	}
+}
+
+func TestCopyErrors(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	makeTree(t, src, "a.txt", "b.txt", "c/d.txt")
+	// Files cannot replace the directory b.txt nor be created below the file c.
+	makeTree(t, dst, "b.txt/", "c")
+	err := CopyDirWithOptions(src, dst, CopyOptions{})
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Errorf("CopyDirWithOptions(): got %v, want 2 errors", err)
+	}
+	if got, _ := Read(filepath.Join(dst, "a.txt")); got != "a.txt" {
+		t.Errorf("CopyDirWithOptions(): got %v, want a.txt copied", got)
+	}
+
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"dir missing", func() error { return CopyDirWithOptions(filepath.Join(src, "x"), dst, CopyOptions{}) }},
+		{"dir of file", func() error { return CopyDirWithOptions(filepath.Join(src, "a.txt"), dst, CopyOptions{}) }},
+		{"file of dir", func() error { return CopyFileWithOptions(filepath.Join(src, "c"), dst, CopyOptions{}) }},
+		{"file missing", func() error { return CopyFileWithOptions(filepath.Join(src, "x"), dst, CopyOptions{}) }},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); err == nil {
+			t.Errorf("%v: got %v, want error", tt.name, err)
+		}
+	}
+	if err := CopyDir(filepath.Join(src, "x"), dst); !os.IsNotExist(err) {
+		t.Errorf("CopyDir(): got %v, want not exist", err)
+	}
+}
+
+func TestCopyProgress(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	makeTree(t, src, "a.txt", "b/c.txt")
+	if err := os.WriteFile(filepath.Join(src, "big.bin"), make([]byte, 1<<20), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var reports []CopyProgress
+	opts := CopyOptions{ProgressInterval: time.Nanosecond, Progress: func(p CopyProgress) { reports = append(reports, p) }}
+	if err := CopyDirWithOptions(src, dst, opts); err != nil {
+		t.Fatalf("CopyDirWithOptions(): %v", err)
+	}
+	total := int64(1<<20 + len("a.txt") + len("b/c.txt"))
+	last := reports[len(reports)-1]
+	if len(reports) < 4 || last.Files != 3 || last.TotalFiles != 3 || last.Bytes != total || last.TotalBytes != total {
+		t.Errorf("CopyDirWithOptions(): got %v reports, last %+v", len(reports), last)
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i].Bytes < reports[i-1].Bytes || reports[i].Files < reports[i-1].Files {
+			t.Errorf("CopyDirWithOptions(): progress went back from %+v to %+v", reports[i-1], reports[i])
+		}
+	}
+}
+
+func TestCopyProgressString(t *testing.T) {
+	tests := []struct {
+		name string
+		p    CopyProgress
+		want string
+	}{
+		{"bytes", CopyProgress{File: "dir/file.bin", Files: 3, TotalFiles: 10, Bytes: 1 << 20, TotalBytes: 4 << 20, Elapsed: 2 * time.Second},
+			"3/10 files, 1.00 MB/4.00 MB (25%), 512.00 KB/s, dir/file.bin"},
+		{"empty files", CopyProgress{Files: 1, TotalFiles: 4}, "1/4 files, 0.00 B/0.00 B (25%), 0.00 B/s"},
+		{"nothing", CopyProgress{}, "0/0 files, 0.00 B/0.00 B (100%), 0.00 B/s"},
+	}
+	for _, tt := range tests {
+		if got := tt.p.String(); got != tt.want {
+			t.Errorf("CopyProgress.String(): name %v , got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCopyCancel(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	makeTree(t, src, "a.txt")
+	if err := os.WriteFile(filepath.Join(src, "big.bin"), make([]byte, 4<<20), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Cancel in the middle of copying big.bin, which comes after a.txt.
+	opts := CopyOptions{ProgressInterval: time.Nanosecond, Progress: func(p CopyProgress) {
+		if p.Files == 1 && p.Bytes > int64(len("a.txt")) {
+			cancel()
+		}
+	}}
+	err := CopyDirContext(ctx, src, dst, opts)
+	var errs Errors
+	if !errors.Is(err, context.Canceled) || !errors.As(err, &errs) || len(errs) != 1 {
+		t.Errorf("CopyDirContext(): got %v, want %v", err, context.Canceled)
+	}
+	if got := treeFiles(t, dst); got != "a.txt=a.txt" {
+		t.Errorf("CopyDirContext(): got %v, want the partial big.bin removed", got)
+	}
+
+	dst = filepath.Join(t.TempDir(), "big.bin")
+	if err := CopyFileContext(ctx, filepath.Join(src, "big.bin"), dst, CopyOptions{}); !errors.Is(err, context.Canceled) || IsExist(dst) {
+		t.Errorf("CopyFileContext(): got %v, want %v", err, context.Canceled)
+	}
+
+	// A copy cancelled midway over an existing file keeps the old content.
+	dir := t.TempDir()
+	dst = filepath.Join(dir, "big.bin")
+	if err := os.WriteFile(dst, []byte("precious"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	opts = CopyOptions{ProgressInterval: time.Nanosecond, Progress: func(p CopyProgress) {
+		if p.Bytes > 0 {
+			cancel()
+		}
+	}}
+	if err := CopyFileContext(ctx, filepath.Join(src, "big.bin"), dst, opts); !errors.Is(err, context.Canceled) {
+		t.Errorf("CopyFileContext(): got %v, want %v", err, context.Canceled)
+	}
+	if got := treeFiles(t, dir); got != "big.bin=precious" {
+		t.Errorf("CopyFileContext(): got %v, want the old big.bin and no temp file", got)
+	}
+}
@@ -0,0 +1,312 @@
+package ufile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FileType is a set of file types matched by a Filter.
+type FileType uint8
+
+// File types of a Filter. Directories are always walked.
+const (
+	TypeRegular FileType = 1 << iota
+	TypeSymlink
+	// TypeOther matches devices, named pipes and sockets.
+	TypeOther
+)
+
+// FilterConfig configures NewFilter. Zero fields match every file.
+type FilterConfig struct {
+	// Include selects only the files matching these patterns, in .gitignore
+	// syntax: "*" and "?" do not match "/", "**" matches any number of
+	// directories, a pattern with a "/" is relative to the root, one ending
+	// in "/" matches directories and everything below them, and "!" negates.
+	Include []string
+	// Exclude drops the files and directories matching these patterns,
+	// in .gitignore syntax. The last matching pattern wins, so a negated
+	// pattern re-includes files, but not those below an excluded directory.
+	Exclude []string
+	// Dockerignore makes Exclude follow .dockerignore semantics instead:
+	// every pattern is relative to the root, and negated patterns can
+	// re-include files below excluded directories.
+	Dockerignore bool
+	// IgnoreCase matches patterns case-insensitively.
+	IgnoreCase bool
+	// Types selects files of these types.
+	Types FileType
+	// MinSize and MaxSize select files of at least and at most this size.
+	MinSize, MaxSize int64
+	// ModifiedAfter and ModifiedBefore select files modified in this range.
+	ModifiedAfter, ModifiedBefore time.Time
+}
+
+// Filter matches files by path, type, size and modification time.
+// A nil Filter matches every file.
+type Filter struct {
+	cfg     FilterConfig
+	include *matcher
+	exclude *matcher
+}
+
+// NewFilter returns a Filter for cfg, or an error for a malformed pattern.
+func NewFilter(cfg FilterConfig) (*Filter, error) {
+	f := &Filter{cfg: cfg}
+	var err error
+	if len(cfg.Include) > 0 {
+		if f.include, err = newMatcher(cfg.Include, false, cfg.IgnoreCase); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.Exclude) > 0 {
+		if f.exclude, err = newMatcher(cfg.Exclude, cfg.Dockerignore, cfg.IgnoreCase); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// ReadIgnoreFile reads the patterns of a .gitignore or .dockerignore file,
+// without blank lines and comments, for use in FilterConfig.
+func ReadIgnoreFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseIgnore(file)
+}
+
+// ParseIgnore reads patterns in .gitignore syntax from r.
+func ParseIgnore(r io.Reader) ([]string, error) {
+	var patterns []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := trimTrailingSpace(strings.TrimSuffix(scanner.Text(), "\r"))
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// Match reports whether the entry at rel, a slash-separated path relative to
+// the root of a walk, is selected. Directories are selected unless excluded,
+// so that walks descend into them, and the other conditions apply to files.
+func (f *Filter) Match(rel string, entry fs.DirEntry) bool {
+	if f == nil {
+		return true
+	}
+	isDir := entry.IsDir()
+	if f.exclude != nil && f.exclude.match(rel, isDir) {
+		// Negated .dockerignore patterns may re-include files below.
+		return isDir && f.exclude.docker && f.exclude.negations
+	}
+	if isDir {
+		return true
+	}
+	if f.include != nil && !f.include.match(rel, false) {
+		return false
+	}
+	if f.cfg.Types != 0 && f.cfg.Types&fileType(entry.Type()) == 0 {
+		return false
+	}
+	if f.cfg.MinSize == 0 && f.cfg.MaxSize == 0 && f.cfg.ModifiedAfter.IsZero() && f.cfg.ModifiedBefore.IsZero() {
+		return true
+	}
+	info, err := entry.Info()
+	if err != nil {
+		return false
+	}
+	if info.Size() < f.cfg.MinSize || (f.cfg.MaxSize > 0 && info.Size() > f.cfg.MaxSize) {
+		return false
+	}
+	if !f.cfg.ModifiedAfter.IsZero() && !info.ModTime().After(f.cfg.ModifiedAfter) {
+		return false
+	}
+	if !f.cfg.ModifiedBefore.IsZero() && !info.ModTime().Before(f.cfg.ModifiedBefore) {
+		return false
+	}
+	return true
+}
+
+func fileType(mode fs.FileMode) FileType {
+	switch {
+	case mode.IsRegular():
+		return TypeRegular
+	case mode&fs.ModeSymlink != 0:
+		return TypeSymlink
+	}
+	return TypeOther
+}
+
+// rule is a compiled pattern.
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// matcher matches paths against a list of patterns.
+type matcher struct {
+	rules     []rule
+	docker    bool
+	negations bool
+}
+
+func newMatcher(patterns []string, docker, ignoreCase bool) (*matcher, error) {
+	m := &matcher{docker: docker}
+	for _, p := range patterns {
+		var r rule
+		if strings.HasPrefix(p, "!") {
+			r.negate = true
+			m.negations = true
+			p = p[1:]
+		} else if strings.HasPrefix(p, `\!`) || strings.HasPrefix(p, `\#`) {
+			p = p[1:]
+		}
+		if docker {
+			p = path.Clean(strings.TrimPrefix(p, "/"))
+		} else if strings.HasSuffix(p, "/") {
+			r.dirOnly = true
+			p = strings.TrimRight(p, "/")
+		}
+		anchored := docker || strings.Contains(p, "/")
+		expr, err := globRegexp(strings.TrimPrefix(p, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("ufile: pattern %q: %w", p, err)
+		}
+		if !anchored && !strings.HasPrefix(expr, "(?:.*/)?") {
+			expr = "(?:.*/)?" + expr
+		}
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		if r.re, err = regexp.Compile("^" + expr + "$"); err != nil {
+			return nil, fmt.Errorf("ufile: pattern %q: %w", p, err)
+		}
+		m.rules = append(m.rules, r)
+	}
+	return m, nil
+}
+
+// match reports whether the patterns match rel or, as a directory
+// and everything below it is matched, one of the parents of rel.
+func (m *matcher) match(rel string, isDir bool) bool {
+	if m.docker {
+		matched := false
+		for _, r := range m.rules {
+			if r.matchOrParent(rel) {
+				matched = !r.negate
+			}
+		}
+		return matched
+	}
+	for i := 0; i < len(rel); i++ {
+		if rel[i] == '/' && m.matchPath(rel[:i], true) {
+			return true
+		}
+	}
+	return m.matchPath(rel, isDir)
+}
+
+// matchPath applies the .gitignore rules to rel, where the last one matching wins.
+func (m *matcher) matchPath(rel string, isDir bool) bool {
+	matched := false
+	for _, r := range m.rules {
+		if (!r.dirOnly || isDir) && r.re.MatchString(rel) {
+			matched = !r.negate
+		}
+	}
+	return matched
+}
+
+func (r rule) matchOrParent(rel string) bool {
+	for {
+		if r.re.MatchString(rel) {
+			return true
+		}
+		i := strings.LastIndexByte(rel, '/')
+		if i < 0 {
+			return false
+		}
+		rel = rel[:i]
+	}
+}
+
+// globRegexp translates a glob pattern to a regular expression.
+func globRegexp(p string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' && (i == 0 || p[i-1] == '/') && (i+2 == len(p) || p[i+2] == '/') {
+				if i+2 == len(p) {
+					b.WriteString(".*")
+				} else {
+					// "**/" matches zero or more directories.
+					b.WriteString("(?:.*/)?")
+					i++
+				}
+				i++
+				continue
+			}
+			for i+1 < len(p) && p[i+1] == '*' {
+				i++
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := i + 1
+			if end < len(p) && (p[end] == '!' || p[end] == '^') {
+				end++
+			}
+			if end < len(p) && p[end] == ']' {
+				end++
+			}
+			for end < len(p) && p[end] != ']' {
+				if p[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(p) {
+				return "", errors.New("unterminated character class")
+			}
+			class := p[i+1 : end]
+			if class != "" && class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i = end
+		case '\\':
+			if i+1 < len(p) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String(), nil
+}
+
+// trimTrailingSpace removes the trailing spaces of a pattern line
+// that are not escaped with a backslash.
+func trimTrailingSpace(s string) string {
+	for strings.HasSuffix(s, " ") && !strings.HasSuffix(s, `\ `) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
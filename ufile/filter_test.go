@@ -0,0 +1,165 @@
+package ufile
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEntry is a DirEntry of a file or directory without info.
+type testEntry struct {
+	name string
+	dir  bool
+}
+
+func (e testEntry) Name() string               { return path.Base(e.name) }
+func (e testEntry) IsDir() bool                { return e.dir }
+func (e testEntry) Info() (fs.FileInfo, error) { return nil, fs.ErrNotExist }
+
+func (e testEntry) Type() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+func TestFilterPatterns(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  FilterConfig
+		path string
+		dir  bool
+		want bool
+	}{
+		{"name anywhere", FilterConfig{Exclude: []string{"*.log"}}, "a/b/c.log", false, false},
+		{"name no match", FilterConfig{Exclude: []string{"*.log"}}, "a/b/c.txt", false, true},
+		{"star not slash", FilterConfig{Exclude: []string{"a/*.log"}}, "a/b/c.log", false, true},
+		{"anchored", FilterConfig{Exclude: []string{"/build"}}, "src/build", true, true},
+		{"anchored root", FilterConfig{Exclude: []string{"/build"}}, "build", true, false},
+		{"middle slash anchors", FilterConfig{Exclude: []string{"doc/frotz"}}, "a/doc/frotz", false, true},
+		{"leading double star", FilterConfig{Exclude: []string{"**/foo/bar"}}, "x/y/foo/bar", false, false},
+		{"middle double star", FilterConfig{Exclude: []string{"a/**/b"}}, "a/b", false, false},
+		{"middle double star deep", FilterConfig{Exclude: []string{"a/**/b"}}, "a/x/y/b", false, false},
+		{"trailing double star", FilterConfig{Exclude: []string{"abc/**"}}, "abc/x/y", false, false},
+		{"trailing double star dir", FilterConfig{Exclude: []string{"abc/**"}}, "abc", true, true},
+		{"dir only dir", FilterConfig{Exclude: []string{"tmp/"}}, "a/tmp", true, false},
+		{"dir only file", FilterConfig{Exclude: []string{"tmp/"}}, "a/tmp", false, true},
+		{"dir only below", FilterConfig{Exclude: []string{"tmp/"}}, "tmp/x/y.txt", false, false},
+		{"negation", FilterConfig{Exclude: []string{"*.log", "!keep.log"}}, "a/keep.log", false, true},
+		{"negation below excluded dir", FilterConfig{Exclude: []string{"logs/", "!logs/keep.log"}}, "logs/keep.log", false, false},
+		{"last rule wins", FilterConfig{Exclude: []string{"!a.txt", "*.txt"}}, "a.txt", false, false},
+		{"class", FilterConfig{Exclude: []string{"file[0-9].txt"}}, "file7.txt", false, false},
+		{"negated class", FilterConfig{Exclude: []string{"file[!0-9].txt"}}, "file7.txt", false, true},
+		{"question", FilterConfig{Exclude: []string{"?.go"}}, "ab.go", false, true},
+		{"escaped", FilterConfig{Exclude: []string{`\!important`}}, "!important", false, false},
+		{"case sensitive", FilterConfig{Exclude: []string{"*.JPG"}}, "a.jpg", false, true},
+		{"ignore case", FilterConfig{Exclude: []string{"*.JPG"}, IgnoreCase: true}, "a.jpg", false, false},
+		{"docker anchored", FilterConfig{Exclude: []string{"*.md"}, Dockerignore: true}, "docs/a.md", false, true},
+		{"docker root", FilterConfig{Exclude: []string{"*.md"}, Dockerignore: true}, "a.md", false, false},
+		{"docker negation below dir", FilterConfig{Exclude: []string{"docs", "!docs/README.md"}, Dockerignore: true}, "docs/README.md", false, true},
+		{"docker excluded below dir", FilterConfig{Exclude: []string{"docs", "!docs/README.md"}, Dockerignore: true}, "docs/a.md", false, false},
+		{"docker walks excluded dir", FilterConfig{Exclude: []string{"docs", "!docs/README.md"}, Dockerignore: true}, "docs", true, true},
+		{"include", FilterConfig{Include: []string{"src/**/*.go"}}, "src/a/b.go", false, true},
+		{"include miss", FilterConfig{Include: []string{"src/**/*.go"}}, "test/b.go", false, false},
+		{"include walks dirs", FilterConfig{Include: []string{"*.go"}}, "test", true, true},
+		{"include dir", FilterConfig{Include: []string{"docs/"}}, "docs/a/b.md", false, true},
+		{"include and exclude", FilterConfig{Include: []string{"*.go"}, Exclude: []string{"*_test.go"}}, "a_test.go", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewFilter(): %v", err)
+			}
+			if got := f.Match(tt.path, testEntry{tt.path, tt.dir}); got != tt.want {
+				t.Errorf("Match(): name %v , got %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFilterError(t *testing.T) {
+	if _, err := NewFilter(FilterConfig{Exclude: []string{"a[b"}}); err == nil || !strings.Contains(err.Error(), `"a[b"`) {
+		t.Errorf("NewFilter(): got %v, want error", err)
+	}
+}
+
+func TestParseIgnore(t *testing.T) {
+	got, err := ParseIgnore(strings.NewReader("# comment\n\n*.log  \r\nbuild/\n\\#file\ntrailing\\ \n"))
+	want := []string{"*.log", "build/", `\#file`, `trailing\ `}
+	if strings.Join(got, "|") != strings.Join(want, "|") || err != nil {
+		t.Errorf("ParseIgnore(): got %q %v, want %q", got, err, want)
+	}
+}
+
+func TestListFilesFilter(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir, "a.go", "a_test.go", "big.bin", "node_modules/m.go", "src/b.go", "src/old.go", ".gitignore")
+	if err := os.WriteFile(filepath.Join(dir, "big.bin"), make([]byte, 4096), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "src", "old.go"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("node_modules/\n*_test.go\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ignore, err := ReadIgnoreFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		t.Fatalf("ReadIgnoreFile(): %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  FilterConfig
+		want string
+	}{
+		{"all", FilterConfig{}, ".gitignore a.go a_test.go big.bin node_modules/m.go src/b.go src/old.go"},
+		{"ignore file", FilterConfig{Exclude: ignore}, ".gitignore a.go big.bin src/b.go src/old.go"},
+		{"include", FilterConfig{Include: []string{"*.go"}, Exclude: ignore}, "a.go src/b.go src/old.go"},
+		{"min size", FilterConfig{MinSize: 1024}, "big.bin"},
+		{"max size", FilterConfig{MaxSize: 5, Include: []string{"/*.go"}}, "a.go"},
+		{"modified after", FilterConfig{ModifiedAfter: time.Now().Add(-time.Hour), Include: []string{"src/"}}, "src/b.go"},
+		{"modified before", FilterConfig{ModifiedBefore: time.Now().Add(-time.Hour)}, "src/old.go"},
+		{"type", FilterConfig{Types: TypeSymlink}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewFilter(): %v", err)
+			}
+			paths, err := ListFilesFilter(dir, f)
+			var got []string
+			for _, p := range paths {
+				rel, _ := filepath.Rel(dir, p)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if strings.Join(got, " ") != tt.want || err != nil {
+				t.Errorf("ListFilesFilter(): name %v , got %v %v, want %v", tt.name, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyDirFilter(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	makeTree(t, src, "a.txt", "b.log", "c/d.txt")
+	f, err := NewFilter(FilterConfig{Exclude: []string{"*.log"}})
+	if err != nil {
+		t.Fatalf("NewFilter(): %v", err)
+	}
+	if err := CopyDirFilter(src, dst, f); err != nil {
+		t.Fatalf("CopyDirFilter(): %v", err)
+	}
+	got, _ := ListFiles(dst, nil, true)
+	want := []string{filepath.Join(dst, "a.txt"), filepath.Join(dst, "c", "d.txt")}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("CopyDirFilter(): got %v, want %v", got, want)
+	}
+}
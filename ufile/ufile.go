@@ -2,61 +2,33 @@ package ufile
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"io/fs"
 	"net/http"
 	"os"
-	"path"
 	"path/filepath"
+	"sort"
+	"sync"
 
 	"github.com/xbmlz/guc/uslice"
 )
 
 // CopyDir copies a directory from src to dst.
 func CopyDir(src, dst string) error {
-	files, err := ListFiles(src, nil, true)
-	if err != nil {
-		return err
-	}
-	for _, file := range files {
-		dstFile := path.Join(dst, file[len(src):])
-		dstDir := path.Dir(dstFile)
-		if !IsExist(dstDir) {
-			if err := os.MkdirAll(dstDir, 0755); err != nil {
-				return err
-			}
-		}
-		if err := CopyFile(file, dstFile); err != nil {
-			return err
-		}
-	}
-	return nil
+	return CopyDirWithOptions(src, dst, CopyOptions{})
+}
+
+// CopyDirFilter copies the files of a directory from src to dst
+// that match filter.
+func CopyDirFilter(src, dst string, filter *Filter) error {
+	return CopyDirWithOptions(src, dst, CopyOptions{Filter: filter.Match})
 }
 
 // CopyFile copies a file from src to dst.
 func CopyFile(src, dst string) error {
-	// Open original file
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer srcFile.Close()
-
-	// Create new file, if dir not exist, create it
-	if !IsExist(path.Dir(dst)) {
-		if err := os.MkdirAll(path.Dir(dst), 0755); err != nil {
-			return err
-		}
-	}
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer destFile.Close()
-
-	_, err = io.Copy(destFile, srcFile)
-
-	return err
+	return CopyFileWithOptions(src, dst, CopyOptions{})
 }
 
 // IsDir checks if a path is a directory.
@@ -85,29 +57,68 @@ func MkdirAll(path string) error {
 	return nil
 }
 
-// ListFiles lists all files in a directory.
+// ListFiles lists all files in a directory, sorted by path. Without recursive
+// it also lists the subdirectories of dir. Errors reading subdirectories are
+// returned as Errors along with the files found elsewhere.
 func ListFiles(dir string, exts []string, recursive bool) ([]string, error) {
+	var mu sync.Mutex
 	var paths []string
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, err
-	}
-	for _, entry := range entries {
-		fullpath := path.Join(dir, entry.Name())
+	err := Walk(context.Background(), dir, WalkOptions{}, func(fullpath string, entry fs.DirEntry) error {
+		if fullpath == dir {
+			if !entry.IsDir() {
+				return &fs.PathError{Op: "readdir", Path: dir, Err: errors.New("not a directory")}
+			}
+			return nil
+		}
 		if entry.IsDir() && recursive {
-			p, _ := ListFiles(fullpath, exts, recursive)
-			paths = append(paths, p...)
-		} else {
-			if len(exts) == 0 {
-				paths = append(paths, fullpath)
-			} else {
-				if uslice.IsExist[string](exts, filepath.Ext(fullpath)) {
-					paths = append(paths, fullpath)
-				}
+			return nil
+		}
+		if len(exts) == 0 || uslice.IsExist[string](exts, filepath.Ext(fullpath)) {
+			mu.Lock()
+			paths = append(paths, fullpath)
+			mu.Unlock()
+		}
+		if entry.IsDir() {
+			return SkipDir
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	return paths, err
+}
+
+// ListFilesFilter lists the files in a directory and its subdirectories
+// that match filter, sorted by path. Directories the filter excludes are
+// not walked.
+func ListFilesFilter(dir string, filter *Filter) ([]string, error) {
+	var mu sync.Mutex
+	var paths []string
+	err := Walk(context.Background(), dir, WalkOptions{}, func(fullpath string, entry fs.DirEntry) error {
+		if fullpath == dir {
+			if !entry.IsDir() {
+				return &fs.PathError{Op: "readdir", Path: dir, Err: errors.New("not a directory")}
 			}
+			return nil
 		}
-	}
-	return paths, nil
+		rel, err := filepath.Rel(dir, fullpath)
+		if err != nil {
+			return err
+		}
+		if !filter.Match(filepath.ToSlash(rel), entry) {
+			if entry.IsDir() {
+				return SkipDir
+			}
+			return nil
+		}
+		if !entry.IsDir() {
+			mu.Lock()
+			paths = append(paths, fullpath)
+			mu.Unlock()
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	return paths, err
 }
 
 // GetMimeType returns the mime type of a file.
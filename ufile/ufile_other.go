@@ -0,0 +1,18 @@
+//go:build windows || plan9
+
+package ufile
+
+import "os"
+
+// chown does nothing as files have no numeric owners on this platform.
+func chown(file *os.File, info os.FileInfo) {}
+
+// syncDir does nothing as directories cannot be synced on this platform.
+func syncDir(dir string) error {
+	return nil
+}
+
+// lchown does nothing as files have no numeric owners on this platform.
+func lchown(name string, info os.FileInfo) error {
+	return nil
+}
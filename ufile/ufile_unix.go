@@ -0,0 +1,40 @@
+//go:build !windows && !plan9
+
+package ufile
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// chown gives file the owner of info, ignoring errors as only
+// privileged processes may give files away.
+func chown(file *os.File, info os.FileInfo) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		_ = file.Chown(int(st.Uid), int(st.Gid))
+	}
+}
+
+// syncDir syncs the directory dir so that a rename in it is durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = d.Close()
+	}()
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+	return nil
+}
+
+// lchown gives name the owner of info, or the link itself for a symbolic link.
+func lchown(name string, info os.FileInfo) error {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return os.Lchown(name, int(st.Uid), int(st.Gid))
+	}
+	return nil
+}
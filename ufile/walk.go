@@ -0,0 +1,234 @@
+package ufile
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// SkipDir is returned by a WalkFunc to skip the directory it was called for.
+var SkipDir = filepath.SkipDir
+
+// walkBatch is the number of directory entries read at a time.
+const walkBatch = 1024
+
+// WalkFunc is called by Walk for the root and every entry below it. The path
+// is the root joined with the path of the entry. Returning SkipDir for a
+// directory skips its subtree, and any other error stops the walk.
+type WalkFunc func(path string, entry fs.DirEntry) error
+
+// WalkOptions configures Walk.
+type WalkOptions struct {
+	// Workers is the number of directories read concurrently,
+	// runtime.NumCPU() by default.
+	Workers int
+}
+
+// Errors is a list of errors of an operation that continues after failures.
+type Errors []error
+
+// Error returns the errors one per line.
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Is reports whether any of the errors matches target.
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors that matches target.
+func (e Errors) As(target any) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Walk walks the tree rooted at root, calling fn for each entry. Directories
+// are read in batches by a pool of workers, so fn is called concurrently and
+// in no particular order, and memory does not grow with the size of the tree.
+// A symbolic link as root is followed, and the links below it are not.
+//
+// An error on root itself, from os.Stat or fn, is returned as is. Errors
+// reading directories below are collected and the walk goes on with the rest
+// of the tree. The walk stops when fn returns an error or ctx is done. Walk
+// then returns Errors holding every error, including that of fn or ctx.
+func Walk(ctx context.Context, root string, opts WalkOptions, fn WalkFunc) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if err := fn(root, fs.FileInfoToDirEntry(info)); err != nil {
+		if err == SkipDir {
+			return nil
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return nil
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	w := &walker{ctx: ctx, fn: fn, queue: []string{root}, pending: 1}
+	w.cond = sync.NewCond(&w.mu)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	wg.Wait()
+
+	if w.stopErr != nil {
+		w.errs = append(w.errs, w.stopErr)
+	}
+	if len(w.errs) == 0 {
+		return nil
+	}
+	return w.errs
+}
+
+// walker holds the state of a Walk shared by its workers.
+type walker struct {
+	ctx context.Context
+	fn  WalkFunc
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// queue holds the directories to read.
+	queue []string
+	// pending counts the directories queued or being read.
+	pending int
+	errs    Errors
+	stopErr error
+	// done is set to 1 when the walk stops.
+	done int32
+}
+
+// work reads directories from the queue until the walk is done.
+func (w *walker) work() {
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && w.pending > 0 {
+			w.cond.Wait()
+		}
+		if len(w.queue) == 0 {
+			w.mu.Unlock()
+			return
+		}
+		dir := w.queue[len(w.queue)-1]
+		w.queue = w.queue[:len(w.queue)-1]
+		w.mu.Unlock()
+
+		w.readDir(dir)
+
+		w.mu.Lock()
+		w.pending--
+		if w.pending == 0 {
+			w.cond.Broadcast()
+		}
+		w.mu.Unlock()
+	}
+}
+
+// readDir calls fn for the entries of dir and queues its subdirectories.
+func (w *walker) readDir(dir string) {
+	f, err := os.Open(dir)
+	if err != nil {
+		w.addError(err)
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	for {
+		if err := w.ctx.Err(); err != nil {
+			w.stop(err)
+			return
+		}
+		entries, err := f.ReadDir(walkBatch)
+		for _, entry := range entries {
+			if w.stopped() {
+				return
+			}
+			path := filepath.Join(dir, entry.Name())
+			if err := w.fn(path, entry); err != nil {
+				if err != SkipDir {
+					w.stop(err)
+					return
+				}
+				continue
+			}
+			if entry.IsDir() {
+				w.push(path)
+			}
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			w.addError(err)
+			return
+		}
+	}
+}
+
+func (w *walker) push(dir string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopErr != nil {
+		return
+	}
+	w.queue = append(w.queue, dir)
+	w.pending++
+	w.cond.Signal()
+}
+
+func (w *walker) addError(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.errs = append(w.errs, err)
+}
+
+// stop ends the walk with err, dropping the queued directories.
+func (w *walker) stop(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopErr == nil {
+		w.stopErr = err
+		atomic.StoreInt32(&w.done, 1)
+	}
+	w.pending -= len(w.queue)
+	w.queue = nil
+	if w.pending == 0 {
+		w.cond.Broadcast()
+	}
+}
+
+func (w *walker) stopped() bool {
+	return atomic.LoadInt32(&w.done) == 1
+}
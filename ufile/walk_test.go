@@ -0,0 +1,176 @@
+package ufile
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// makeTree creates the files in dir, with directories for paths ending in "/".
+func makeTree(t *testing.T, dir string, paths ...string) {
+	t.Helper()
+	for _, p := range paths {
+		full := filepath.Join(dir, filepath.FromSlash(p))
+		if strings.HasSuffix(p, "/") {
+			if err := os.MkdirAll(full, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(p), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// walkPaths walks dir and returns the sorted slash paths relative to dir.
+func walkPaths(t *testing.T, ctx context.Context, dir string, fn WalkFunc) ([]string, error) {
+	t.Helper()
+	var mu sync.Mutex
+	var paths []string
+	err := Walk(ctx, dir, WalkOptions{Workers: 4}, func(path string, entry fs.DirEntry) error {
+		rel, _ := filepath.Rel(dir, path)
+		mu.Lock()
+		paths = append(paths, filepath.ToSlash(rel))
+		mu.Unlock()
+		if fn != nil {
+			return fn(path, entry)
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	return paths, err
+}
+
+func TestWalk(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir, "a.txt", "b/c.txt", "b/d/e.txt", "f/", "node_modules/x/y.js")
+
+	tests := []struct {
+		name string
+		fn   WalkFunc
+		want string
+	}{
+		{"all", nil, ". a.txt b b/c.txt b/d b/d/e.txt f node_modules node_modules/x node_modules/x/y.js"},
+		{"skip dir", func(path string, entry fs.DirEntry) error {
+			if entry.IsDir() && entry.Name() == "node_modules" {
+				return SkipDir
+			}
+			return nil
+		}, ". a.txt b b/c.txt b/d b/d/e.txt f node_modules"},
+		{"skip file", func(path string, entry fs.DirEntry) error {
+			if !entry.IsDir() {
+				return SkipDir
+			}
+			return nil
+		}, ". a.txt b b/c.txt b/d b/d/e.txt f node_modules node_modules/x node_modules/x/y.js"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := walkPaths(t, context.Background(), dir, tt.fn)
+			if strings.Join(got, " ") != tt.want || err != nil {
+				t.Errorf("Walk(): name %v , got %v %v, want %v", tt.name, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkLargeDir(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < walkBatch*2+10; i++ {
+		paths = append(paths, "d/"+strconv.Itoa(i)+".txt")
+	}
+	makeTree(t, dir, paths...)
+	got, err := walkPaths(t, context.Background(), dir, nil)
+	if len(got) != len(paths)+2 || err != nil {
+		t.Errorf("Walk(): got %v entries %v, want %v", len(got), err, len(paths)+2)
+	}
+}
+
+func TestWalkErrors(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir, "a/1.txt", "b/2.txt", "c/3.txt")
+
+	// Removing a directory before it is read makes reading it fail,
+	// and the walk goes on with the others.
+	got, err := walkPaths(t, context.Background(), dir, func(path string, entry fs.DirEntry) error {
+		if entry.Name() == "b" {
+			return os.RemoveAll(path)
+		}
+		return nil
+	})
+	var perr *fs.PathError
+	if strings.Join(got, " ") != ". a a/1.txt b c c/3.txt" || !errors.As(err, &perr) || len(err.(Errors)) != 1 {
+		t.Errorf("Walk(): got %v %v", got, err)
+	}
+
+	stop := errors.New("stop")
+	_, err = walkPaths(t, context.Background(), dir, func(path string, entry fs.DirEntry) error {
+		if entry.Name() == "c" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Walk(): got %v, want %v", err, stop)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	got, err = walkPaths(t, ctx, dir, nil)
+	if strings.Join(got, " ") != "." || !errors.Is(err, context.Canceled) {
+		t.Errorf("Walk(): got %v %v, want %v", got, err, context.Canceled)
+	}
+
+	if _, err := walkPaths(t, context.Background(), filepath.Join(dir, "missing"), nil); !os.IsNotExist(err) {
+		t.Errorf("Walk(): got %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestListFilesErrors(t *testing.T) {
+	if _, err := ListFiles("../testdata/test.txt", nil, true); err == nil {
+		t.Errorf("ListFiles(): got %v, want error", err)
+	}
+	if _, err := ListFiles("../testdata/missing", nil, true); !os.IsNotExist(err) {
+		t.Errorf("ListFiles(): got %v, want not exist", err)
+	}
+	dir := t.TempDir()
+	makeTree(t, dir, "a.txt", "b.png", "c/d.txt")
+	paths, err := ListFiles(dir, []string{".txt"}, false)
+	if strings.Join(paths, " ") != filepath.Join(dir, "a.txt") || err != nil {
+		t.Errorf("ListFiles(): got %v %v", paths, err)
+	}
+}
+
+func TestListFilesSymlinkRoot(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	dir := t.TempDir()
+	makeTree(t, dir, "real/a.txt", "real/b/c.txt")
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink("real", link); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := ListFiles(link, nil, true)
+	want := []string{filepath.Join(link, "a.txt"), filepath.Join(link, "b", "c.txt")}
+	if strings.Join(paths, " ") != strings.Join(want, " ") || err != nil {
+		t.Errorf("ListFiles(): got %v %v, want %v", paths, err, want)
+	}
+	f, _ := NewFilter(FilterConfig{Include: []string{"b/"}})
+	if paths, err := ListFilesFilter(link, f); strings.Join(paths, " ") != want[1] || err != nil {
+		t.Errorf("ListFilesFilter(): got %v %v, want %v", paths, err, want[1])
+	}
+}
@@ -0,0 +1,188 @@
+package ulog
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrClosed is returned when writing to a closed AsyncWriter.
+var ErrClosed = errors.New("ulog: writer closed")
+
+// OverflowPolicy is what an AsyncWriter does when its buffer is full.
+type OverflowPolicy int8
+
+const (
+	// OverflowBlock blocks the write until there is room in the buffer.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop drops the record and counts it in Dropped.
+	OverflowDrop
+)
+
+// AsyncConfig configures NewAsyncWriter.
+type AsyncConfig struct {
+	// Size is the number of records the buffer holds, 1024 by default.
+	Size int
+	// Overflow is what happens to writes when the buffer is full.
+	Overflow OverflowPolicy
+}
+
+// AsyncWriter is an io.Writer that queues records in a bounded ring buffer
+// and writes them to another writer from a single goroutine, in order.
+// Call Close on shutdown to drain the buffer. It is safe for concurrent use.
+type AsyncWriter struct {
+	w   io.Writer
+	cfg AsyncConfig
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	idle     *sync.Cond
+	ring     [][]byte
+	head     int
+	count    int
+	inflight int
+	closed   bool
+	err      error
+	dropped  uint64
+	written  uint64
+	done     chan struct{}
+}
+
+// NewAsyncWriter returns an AsyncWriter that writes to w and starts its goroutine.
+func NewAsyncWriter(w io.Writer, cfg AsyncConfig) *AsyncWriter {
+	if cfg.Size <= 0 {
+		cfg.Size = 1024
+	}
+	a := &AsyncWriter{
+		w:    w,
+		cfg:  cfg,
+		ring: make([][]byte, cfg.Size),
+		done: make(chan struct{}),
+	}
+	a.notEmpty = sync.NewCond(&a.mu)
+	a.notFull = sync.NewCond(&a.mu)
+	a.idle = sync.NewCond(&a.mu)
+	go a.run()
+	return a
+}
+
+// Write queues a copy of p. If the buffer is full it blocks or drops p as
+// configured; a dropped write still reports success.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for !a.closed && a.count == len(a.ring) && a.cfg.Overflow == OverflowBlock {
+		a.notFull.Wait()
+	}
+	if a.closed {
+		return 0, ErrClosed
+	}
+	if a.count == len(a.ring) {
+		a.dropped++
+		return len(p), nil
+	}
+	a.ring[(a.head+a.count)%len(a.ring)] = append([]byte(nil), p...)
+	a.count++
+	a.notEmpty.Signal()
+	return len(p), nil
+}
+
+// run writes the queued records until the writer is closed and drained.
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+	batch := make([][]byte, 0, len(a.ring))
+	a.mu.Lock()
+	for {
+		for a.count == 0 && !a.closed {
+			a.notEmpty.Wait()
+		}
+		if a.count == 0 {
+			a.mu.Unlock()
+			return
+		}
+		batch = batch[:0]
+		for ; a.count > 0; a.count-- {
+			batch = append(batch, a.ring[a.head])
+			a.ring[a.head] = nil
+			a.head = (a.head + 1) % len(a.ring)
+		}
+		a.inflight = len(batch)
+		a.notFull.Broadcast()
+		a.mu.Unlock()
+
+		var err error
+		for _, p := range batch {
+			if _, werr := a.w.Write(p); werr != nil && err == nil {
+				err = werr
+			}
+		}
+
+		a.mu.Lock()
+		a.inflight = 0
+		a.written += uint64(len(batch))
+		if err != nil && a.err == nil {
+			a.err = err
+		}
+		a.idle.Broadcast()
+	}
+}
+
+// drain waits until all queued records are written. It must be called with a.mu held.
+func (a *AsyncWriter) drain() {
+	for a.count > 0 || a.inflight > 0 {
+		a.idle.Wait()
+	}
+}
+
+// Sync waits until the queued records are written and syncs the underlying
+// writer if it has a Sync method. It returns the first write error.
+func (a *AsyncWriter) Sync() error {
+	a.mu.Lock()
+	a.drain()
+	err := a.err
+	a.mu.Unlock()
+	if s, ok := a.w.(interface{ Sync() error }); ok {
+		if serr := s.Sync(); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// Close stops accepting writes, waits until the queued records are written
+// and syncs the underlying writer. The underlying writer is not closed.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.notEmpty.Broadcast()
+	a.notFull.Broadcast()
+	a.mu.Unlock()
+	<-a.done
+	return a.Sync()
+}
+
+// Dropped returns the number of records dropped because the buffer was full.
+func (a *AsyncWriter) Dropped() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.dropped
+}
+
+// Queued returns the number of records accepted but not written yet.
+func (a *AsyncWriter) Queued() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.count + a.inflight
+}
+
+// Written returns the number of records written to the underlying writer.
+func (a *AsyncWriter) Written() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.written
+}
@@ -0,0 +1,112 @@
+package ulog
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// gateWriter blocks writes until open is closed.
+type gateWriter struct {
+	open chan struct{}
+	mu   sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	<-w.open
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriterOrder(t *testing.T) {
+	w := &gateWriter{open: make(chan struct{})}
+	close(w.open)
+	a := NewAsyncWriter(w, AsyncConfig{Size: 16})
+	l := NewStdLogger(a, WithEncoder(NewTextEncoder(EncoderConfig{TimeKey: OmitKey, LevelKey: OmitKey})))
+	var want strings.Builder
+	for i := 0; i < 1000; i++ {
+		l.Info(i)
+		fmt.Fprintf(&want, "msg=%d\n", i)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+	if got := w.String(); got != want.String() {
+		t.Errorf("AsyncWriter: records out of order")
+	}
+	if a.Written() != 1000 || a.Queued() != 0 || a.Dropped() != 0 {
+		t.Errorf("AsyncWriter: got written %v queued %v dropped %v", a.Written(), a.Queued(), a.Dropped())
+	}
+	if _, err := a.Write([]byte("late\n")); err != ErrClosed {
+		t.Errorf("Write(): got %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestAsyncWriterDrop(t *testing.T) {
+	w := &gateWriter{open: make(chan struct{})}
+	a := NewAsyncWriter(w, AsyncConfig{Size: 4, Overflow: OverflowDrop})
+	// The writer goroutine may take a batch of records before it blocks.
+	for i := 0; i < 10; i++ {
+		if _, err := a.Write([]byte(fmt.Sprintf("%d\n", i))); err != nil {
+			t.Fatalf("Write(): %v", err)
+		}
+	}
+	if queued := a.Queued(); queued < 4 || queued > 8 {
+		t.Errorf("Queued(): got %v, want 4 to 8", queued)
+	}
+	close(w.open)
+	if err := a.Sync(); err != nil {
+		t.Fatalf("Sync(): %v", err)
+	}
+	written := a.Written()
+	if written+a.Dropped() != 10 || !strings.HasPrefix(w.String(), "0\n1\n2\n3\n") {
+		t.Errorf("AsyncWriter: got written %v dropped %v output %q", written, a.Dropped(), w.String())
+	}
+	a.Close()
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	w := &gateWriter{open: make(chan struct{})}
+	a := NewAsyncWriter(w, AsyncConfig{Size: 2})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			a.Write([]byte(fmt.Sprintf("%d\n", i)))
+		}(i)
+	}
+	close(w.open)
+	wg.Wait()
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+	if got := strings.Count(w.String(), "\n"); got != 8 || a.Dropped() != 0 {
+		t.Errorf("AsyncWriter: got %v records and %v dropped, want 8 and 0", got, a.Dropped())
+	}
+}
+
+func TestAsyncWriterFatal(t *testing.T) {
+	w := &gateWriter{open: make(chan struct{})}
+	close(w.open)
+	a := NewAsyncWriter(w, AsyncConfig{})
+	l := NewStdLogger(a, WithExitFunc(func(int) {
+		// Fatal syncs the writer before exiting.
+		if !strings.Contains(w.String(), "[FATAL]") {
+			t.Errorf("Fatal(): exit before the record was written")
+		}
+	}))
+	l.Info("info")
+	l.Fatal("fatal")
+	a.Close()
+}
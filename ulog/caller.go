@@ -0,0 +1,114 @@
+package ulog
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// pkgPath is the import path of this package, used to skip its frames.
+var pkgPath = reflect.TypeOf(stdLogger{}).PkgPath()
+
+// maxStackDepth is the number of frames captured for callers and stack traces.
+const maxStackDepth = 64
+
+// internalFrame reports whether f belongs to this package or log/slog,
+// excluding tests of this package.
+func internalFrame(f runtime.Frame) bool {
+	if strings.HasSuffix(f.File, "_test.go") {
+		return false
+	}
+	return strings.HasPrefix(f.Function, pkgPath+".") || strings.HasPrefix(f.Function, "log/slog.")
+}
+
+// callerPCs returns the call stack starting at the first frame outside this
+// package, after skipping skip more frames for wrapper libraries.
+func callerPCs(skip int) []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(2, pcs)
+	for i := 0; i < n; i++ {
+		f, _ := runtime.CallersFrames(pcs[i : i+1]).Next()
+		if internalFrame(f) {
+			continue
+		}
+		if i+skip >= n {
+			return nil
+		}
+		return pcs[i+skip : n]
+	}
+	return nil
+}
+
+// frameOf returns the frame of a single pc.
+func frameOf(pc uintptr) runtime.Frame {
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return f
+}
+
+// shortCaller returns the file:line of f with only the last directory, e.g. "ulog/ulog.go:42".
+func shortCaller(f runtime.Frame) string {
+	file := f.File
+	if i := strings.LastIndexByte(file, '/'); i >= 0 {
+		if j := strings.LastIndexByte(file[:i], '/'); j >= 0 {
+			file = file[j+1:]
+		}
+	}
+	return fmt.Sprintf("%s:%d", file, f.Line)
+}
+
+// shortFunction returns the function name of f without the import path
+// directories, e.g. "ulog.(*stdLogger).Info".
+func shortFunction(f runtime.Frame) string {
+	fn := f.Function
+	if i := strings.LastIndexByte(fn, '/'); i >= 0 {
+		fn = fn[i+1:]
+	}
+	return fn
+}
+
+// formatStack formats pcs like runtime/debug.Stack, one function and
+// file:line pair per frame.
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		if f.Function != "" {
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// addCaller sets the caller, function and stack of r as configured by c.
+// pc is the caller reported by log/slog, or 0 to find it from the call stack.
+func (c *core) addCaller(r *Record, pc uintptr) {
+	stack := r.Level >= c.stackLevel
+	if !c.caller && !c.function && !stack {
+		return
+	}
+	var pcs []uintptr
+	if pc == 0 || stack {
+		pcs = callerPCs(c.skip)
+		if len(pcs) == 0 {
+			return
+		}
+		if pc == 0 {
+			pc = pcs[0]
+		}
+	}
+	f := frameOf(pc)
+	if c.caller {
+		r.Caller = shortCaller(f)
+	}
+	if c.function {
+		r.Function = shortFunction(f)
+	}
+	if stack {
+		r.Stack = formatStack(pcs)
+	}
+}
@@ -0,0 +1,85 @@
+package ulog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// line returns the line of its caller.
+func line() int {
+	_, _, l, _ := runtime.Caller(1)
+	return l
+}
+
+// wrappedInfo is a wrapper library function that needs a caller skip of 1.
+func wrappedInfo(l Logger, msg string) {
+	l.Info(msg)
+}
+
+func TestWithCaller(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(&buf, WithEncoder(NewTextEncoder(EncoderConfig{TimeKey: OmitKey})), WithCaller(true), WithCallerFunction(true))
+	want := line() + 1
+	l.Info("hello")
+	wantLog := line() + 1
+	l.With("a", 1).Log(LevelWarn, MessageKey, "log")
+	lines := strings.Split(buf.String(), "\n")
+	if w := fmt.Sprintf("level=INFO msg=hello caller=ulog/caller_test.go:%d func=ulog.TestWithCaller", want); lines[0] != w {
+		t.Errorf("WithCaller(): got %v, want %v", lines[0], w)
+	}
+	if w := fmt.Sprintf("caller=ulog/caller_test.go:%d", wantLog); !strings.Contains(lines[1], w) {
+		t.Errorf("WithCaller(): got %v, want %v", lines[1], w)
+	}
+}
+
+func TestWithCallerSkip(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(&buf, WithCaller(true), WithCallerSkip(1))
+	want := line() + 1
+	wrappedInfo(l, "hello")
+	if w := fmt.Sprintf(" ulog/caller_test.go:%d hello\n", want); !strings.HasSuffix(buf.String(), w) {
+		t.Errorf("WithCallerSkip(): got %q, want suffix %q", buf.String(), w)
+	}
+}
+
+func TestWithStacktrace(t *testing.T) {
+	tests := []struct {
+		name  string
+		enc   Encoder
+		check func(out string) bool
+	}{
+		{"std", stdEncoder{}, func(out string) bool {
+			return strings.Contains(out, "error\n"+pkgPath+".TestWithStacktrace") && strings.Count(out, "\n\t") > 0
+		}},
+		{"text", NewTextEncoder(EncoderConfig{}), func(out string) bool {
+			return strings.Count(out, "\n") == 1 && strings.Contains(out, `stack="`+pkgPath+".TestWithStacktrace")
+		}},
+		{"json", NewJSONEncoder(EncoderConfig{}), func(out string) bool {
+			var m map[string]any
+			if err := json.Unmarshal([]byte(out), &m); err != nil {
+				return false
+			}
+			stack, _ := m[StackKey].(string)
+			return strings.HasPrefix(stack, pkgPath+".TestWithStacktrace")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := NewStdLogger(&buf, WithEncoder(tt.enc), WithStacktrace(LevelError))
+			l.Warn("warn")
+			if strings.Contains(buf.String(), "TestWithStacktrace") {
+				t.Errorf("WithStacktrace(): name %v , got stack below level %q", tt.name, buf.String())
+			}
+			buf.Reset()
+			l.Error("error")
+			if !tt.check(buf.String()) {
+				t.Errorf("WithStacktrace(): name %v , got %q", tt.name, buf.String())
+			}
+		})
+	}
+}
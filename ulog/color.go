@@ -0,0 +1,81 @@
+package ulog
+
+import (
+	"io"
+	"os"
+)
+
+// ColorMode controls whether a logger writes colored level prefixes.
+type ColorMode int8
+
+const (
+	// ColorAuto colors the output if ColorEnabled reports true for the writer.
+	ColorAuto ColorMode = iota
+	// ColorAlways always colors the output.
+	ColorAlways
+	// ColorNever never colors the output.
+	ColorNever
+)
+
+const colorReset = "\033[0m"
+
+// Theme maps levels to the ANSI escape sequences used to color them.
+// Levels without an entry are not colored.
+type Theme map[Level]string
+
+// DefaultTheme colors trace cyan, debug blue, info green, warn yellow,
+// error red, and panic and fatal magenta.
+var DefaultTheme = Theme{
+	LevelTrace: "\033[36m",
+	LevelDebug: "\033[34m",
+	LevelInfo:  "\033[32m",
+	LevelWarn:  "\033[33m",
+	LevelError: "\033[31m",
+	LevelPanic: "\033[35m",
+	LevelFatal: "\033[35m",
+}
+
+// colorEncoder is implemented by encoders that can color their output.
+type colorEncoder interface {
+	withTheme(theme Theme) Encoder
+}
+
+// ColorEnabled reports whether colored output should be written to w.
+// A non-empty NO_COLOR disables colors and a FORCE_COLOR other than "0" or
+// "false" enables them, otherwise colors are enabled if w is a terminal.
+func ColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force, ok := os.LookupEnv("FORCE_COLOR"); ok {
+		return force != "0" && force != "false"
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// isTerminal reports whether w is a character device such as a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize appends s wrapped in the theme color of level.
+func (t Theme) colorize(b []byte, level Level, s string) []byte {
+	color := t[level]
+	if color == "" {
+		return append(b, s...)
+	}
+	b = append(b, color...)
+	b = append(b, s...)
+	return append(b, colorReset...)
+}
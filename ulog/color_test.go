@@ -0,0 +1,71 @@
+package ulog
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestColorEnabled(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		want  bool
+		write bool
+	}{
+		{"buffer", nil, false, false},
+		{"force", map[string]string{"FORCE_COLOR": "1"}, true, false},
+		{"force off", map[string]string{"FORCE_COLOR": "0"}, false, false},
+		{"no color", map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"}, false, false},
+		{"file", nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "")
+			t.Setenv("FORCE_COLOR", "")
+			os.Unsetenv("FORCE_COLOR")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			var got bool
+			if tt.write {
+				f, err := os.CreateTemp(t.TempDir(), "log")
+				if err != nil {
+					t.Fatalf("CreateTemp(): %v", err)
+				}
+				defer f.Close()
+				got = ColorEnabled(f)
+			} else {
+				got = ColorEnabled(&bytes.Buffer{})
+			}
+			if got != tt.want {
+				t.Errorf("ColorEnabled(): name %v , got %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithColor(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"auto", nil, "[INFO ] "},
+		{"always", []Option{WithColor(ColorAlways)}, InfoPrefix},
+		{"never", []Option{WithColor(ColorNever)}, "[INFO ] "},
+		{"theme", []Option{WithColor(ColorAlways), WithTheme(Theme{LevelInfo: "\033[1;36m"})}, "\033[1;36m[INFO ]\033[0m "},
+		{"theme without level", []Option{WithColor(ColorAlways), WithTheme(Theme{LevelError: "\033[31m"})}, "[INFO ] "},
+		{"json", []Option{WithColor(ColorAlways), WithEncoder(NewJSONEncoder(EncoderConfig{}))}, "{"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			NewStdLogger(&buf, tt.opts...).Info("hello")
+			if got := buf.String(); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("WithColor(): name %v , got %q, want prefix %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
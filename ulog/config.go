@@ -0,0 +1,416 @@
+package ulog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xbmlz/guc/ufile"
+)
+
+// Config describes a logger, for loading from a JSON or YAML file or from
+// environment variables with LoadEnv. Empty settings use the defaults of
+// NewStdLogger.
+type Config struct {
+	// Level is the minimum level, e.g. "info".
+	Level string `json:"level" yaml:"level"`
+	// Encoder is "std", "text", "json", "console" or "syslog", "std" by default.
+	Encoder string `json:"encoder" yaml:"encoder"`
+	// TimeLayout is a time layout or an utime layout name for the encoder.
+	TimeLayout string `json:"time_layout" yaml:"time_layout"`
+	// Color is "auto", "always" or "never", "auto" by default.
+	Color string `json:"color" yaml:"color"`
+	// Caller adds the file:line of the log call to records.
+	Caller bool `json:"caller" yaml:"caller"`
+	// Stacktrace is the level from which records get a stack trace.
+	Stacktrace string `json:"stacktrace" yaml:"stacktrace"`
+	// Outputs are "stdout", "stderr" or file paths, "stderr" by default.
+	Outputs []string `json:"outputs" yaml:"outputs"`
+	// Rotation rotates the file outputs.
+	Rotation *RotationConfig `json:"rotation" yaml:"rotation"`
+	// Modules are the levels of named loggers, e.g. {"db": "debug"}.
+	Modules map[string]string `json:"modules" yaml:"modules"`
+	// Sampling samples the records of the logger.
+	Sampling *SamplingConfig `json:"sampling" yaml:"sampling"`
+}
+
+// RotationConfig is the rotation of the file outputs of a Config.
+type RotationConfig struct {
+	// MaxSize is the size at which a file is rotated, e.g. "100MB".
+	MaxSize string `json:"max_size" yaml:"max_size"`
+	// Interval is "hourly" or "daily".
+	Interval string `json:"interval" yaml:"interval"`
+	// MaxAge is the age after which rotated files are removed, e.g. "168h".
+	MaxAge string `json:"max_age" yaml:"max_age"`
+	// MaxBackups is the number of rotated files kept.
+	MaxBackups int `json:"max_backups" yaml:"max_backups"`
+	// Compress gzips rotated files.
+	Compress bool `json:"compress" yaml:"compress"`
+	// UTC uses UTC for interval boundaries and file names.
+	UTC bool `json:"utc" yaml:"utc"`
+}
+
+// SamplingConfig is the sampling of a Config, see SamplerConfig.
+type SamplingConfig struct {
+	// Interval is the period in which records are counted, e.g. "1s".
+	Interval string `json:"interval" yaml:"interval"`
+	// First and Thereafter are those of SamplerConfig. At least one of
+	// them must be set, as otherwise every record would be dropped.
+	First      int `json:"first" yaml:"first"`
+	Thereafter int `json:"thereafter" yaml:"thereafter"`
+}
+
+// ConfigError is an invalid setting of a Config.
+type ConfigError struct {
+	// Field is the path of the setting, e.g. "rotation.max_size".
+	Field string
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("ulog: config %s: %v", e.Field, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+func configError(field string, err error) error {
+	return &ConfigError{Field: field, Err: err}
+}
+
+// Build returns the logger described by c. With several outputs, each one
+// gets its own encoder so only terminals are colored. Build sets the levels
+// of Modules with SetLevelFor; call SetDefault with the logger to make it the
+// parent of named loggers. Invalid settings return a *ConfigError.
+//
+// The caller owns the files and rotate writers opened for Outputs, and closes
+// them with the returned io.Closer once the logger is no longer used.
+// e.g. l, closer, err := Config{Level: "info", Encoder: "json", Outputs: []string{"stdout", "app.log"}}.Build()
+func (c Config) Build() (Logger, io.Closer, error) {
+	opts, err := c.options()
+	if err != nil {
+		return nil, nil, err
+	}
+	level := LevelDebug
+	if c.Level != "" {
+		if level, err = parseConfigLevel("level", c.Level); err != nil {
+			return nil, nil, err
+		}
+	}
+	modules := make(map[string]Level, len(c.Modules))
+	for name, s := range c.Modules {
+		if modules[name], err = parseConfigLevel("modules."+name, s); err != nil {
+			return nil, nil, err
+		}
+	}
+	var sampler *SamplerConfig
+	if c.Sampling != nil {
+		if sampler, err = c.Sampling.samplerConfig(); err != nil {
+			return nil, nil, err
+		}
+	}
+	writers, closer, err := c.writers()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var l Logger
+	if len(writers) == 1 {
+		l = NewStdLogger(writers[0], append(opts, WithLevel(level))...)
+	} else {
+		loggers := make([]Logger, len(writers))
+		for i, w := range writers {
+			loggers[i] = NewStdLogger(w, append(opts, WithLevel(LevelTrace))...)
+		}
+		l = NewMultiLogger(loggers...)
+		l.SetLevel(level)
+	}
+	if sampler != nil {
+		l = NewSampler(l, *sampler)
+	}
+	for name, level := range modules {
+		SetLevelFor(name, level)
+	}
+	return l, closer, nil
+}
+
+// closers closes the outputs opened by Build.
+type closers []io.Closer
+
+func (o closers) Close() error {
+	var errs []string
+	for _, c := range o {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("ulog: close outputs: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func parseConfigLevel(field, s string) (Level, error) {
+	level, err := ParseLevel(s)
+	if err != nil {
+		return level, configError(field, fmt.Errorf("unknown level %q", s))
+	}
+	return level, nil
+}
+
+// options returns the logger options of c besides the level.
+func (c Config) options() ([]Option, error) {
+	var opts []Option
+	switch c.Encoder {
+	case "", "std":
+	case "text":
+		opts = append(opts, WithEncoder(NewTextEncoder(EncoderConfig{TimeLayout: c.TimeLayout})))
+	case "json":
+		opts = append(opts, WithEncoder(NewJSONEncoder(EncoderConfig{TimeLayout: c.TimeLayout})))
+	case "console":
+		opts = append(opts, WithEncoder(NewConsoleEncoder(ConsoleConfig{TimeLayout: c.TimeLayout})))
+	case "syslog":
+		opts = append(opts, WithEncoder(NewSyslogEncoder(SyslogConfig{})))
+	default:
+		return nil, configError("encoder", fmt.Errorf("unknown encoder %q", c.Encoder))
+	}
+	switch c.Color {
+	case "", "auto":
+	case "always":
+		opts = append(opts, WithColor(ColorAlways))
+	case "never":
+		opts = append(opts, WithColor(ColorNever))
+	default:
+		return nil, configError("color", fmt.Errorf("unknown color mode %q", c.Color))
+	}
+	if c.Caller {
+		opts = append(opts, WithCaller(true))
+	}
+	if c.Stacktrace != "" {
+		level, err := parseConfigLevel("stacktrace", c.Stacktrace)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithStacktrace(level))
+	}
+	return opts, nil
+}
+
+// writers opens the outputs of c, returning the writers and what closes them.
+func (c Config) writers() ([]io.Writer, closers, error) {
+	outputs := c.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{"stderr"}
+	}
+	var rotate *RotateConfig
+	if c.Rotation != nil {
+		cfg, err := c.Rotation.rotateConfig()
+		if err != nil {
+			return nil, nil, err
+		}
+		rotate = &cfg
+	}
+	writers := make([]io.Writer, 0, len(outputs))
+	var opened closers
+	for i, output := range outputs {
+		var w io.Writer
+		var err error
+		switch output {
+		case "":
+			err = errors.New("empty output")
+		case "stdout":
+			w = os.Stdout
+		case "stderr":
+			w = os.Stderr
+		default:
+			if rotate != nil {
+				cfg := *rotate
+				cfg.Filename = output
+				w, err = NewRotateWriter(cfg)
+			} else if err = ufile.MkdirAll(filepath.Dir(output)); err == nil {
+				w, err = os.OpenFile(output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+			}
+		}
+		if err != nil {
+			_ = opened.Close()
+			return nil, nil, configError(fmt.Sprintf("outputs[%d]", i), err)
+		}
+		if f, ok := w.(io.Closer); ok && w != os.Stdout && w != os.Stderr {
+			opened = append(opened, f)
+		}
+		writers = append(writers, w)
+	}
+	return writers, opened, nil
+}
+
+func (c RotationConfig) rotateConfig() (RotateConfig, error) {
+	cfg := RotateConfig{MaxBackups: c.MaxBackups, Compress: c.Compress, UTC: c.UTC}
+	var err error
+	if c.MaxSize != "" {
+		if cfg.MaxSize, err = parseSize(c.MaxSize); err != nil {
+			return cfg, configError("rotation.max_size", err)
+		}
+	}
+	switch c.Interval {
+	case "", "none":
+	case "hourly":
+		cfg.Interval = RotateHourly
+	case "daily":
+		cfg.Interval = RotateDaily
+	default:
+		return cfg, configError("rotation.interval", fmt.Errorf("unknown interval %q", c.Interval))
+	}
+	if c.MaxAge != "" {
+		if cfg.MaxAge, err = time.ParseDuration(c.MaxAge); err != nil {
+			return cfg, configError("rotation.max_age", err)
+		}
+	}
+	if c.MaxBackups < 0 {
+		return cfg, configError("rotation.max_backups", fmt.Errorf("negative value %d", c.MaxBackups))
+	}
+	return cfg, nil
+}
+
+func (c SamplingConfig) samplerConfig() (*SamplerConfig, error) {
+	cfg := &SamplerConfig{First: c.First, Thereafter: c.Thereafter}
+	if c.Interval != "" {
+		d, err := time.ParseDuration(c.Interval)
+		if err != nil {
+			return nil, configError("sampling.interval", err)
+		}
+		cfg.Interval = d
+	}
+	if c.First < 0 {
+		return nil, configError("sampling.first", fmt.Errorf("negative value %d", c.First))
+	}
+	if c.Thereafter < 0 {
+		return nil, configError("sampling.thereafter", fmt.Errorf("negative value %d", c.Thereafter))
+	}
+	if c.First == 0 && c.Thereafter == 0 {
+		return nil, configError("sampling.first", errors.New("first or thereafter must be set, or every record is dropped"))
+	}
+	return cfg, nil
+}
+
+// sizeUnits are the units of parseSize, the longest first.
+var sizeUnits = []struct {
+	suffix string
+	size   float64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+}
+
+// parseSize parses a size in bytes such as "512", "10KB" or "1.5 GB", in
+// multiples of 1024 like ufile.FormatSize.
+func parseSize(s string) (int64, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	unit := 1.0
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(t, u.suffix) {
+			t, unit = strings.TrimSpace(strings.TrimSuffix(t, u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(t, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * unit), nil
+}
+
+// LoadEnv sets the settings of c from the environment variables named by
+// prefix and the upper case yaml keys, e.g. APP_LEVEL, APP_OUTPUTS and
+// APP_ROTATION_MAX_SIZE for the prefix "APP". Lists are comma separated and
+// maps are comma separated key=value pairs, e.g. APP_MODULES="db=debug,http=warn".
+func (c *Config) LoadEnv(prefix string) error {
+	return loadEnv(reflect.ValueOf(c).Elem(), strings.ToUpper(prefix))
+}
+
+func loadEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.ToUpper(strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0])
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.Ptr {
+			// Allocate the section only if one of its variables is set.
+			if f.IsNil() {
+				if !envHasPrefix(key + "_") {
+					continue
+				}
+				f.Set(reflect.New(f.Type().Elem()))
+			}
+			if err := loadEnv(f.Elem(), key); err != nil {
+				return err
+			}
+			continue
+		}
+		s, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if err := setEnvField(f, s); err != nil {
+			return configError(key, err)
+		}
+	}
+	return nil
+}
+
+func envHasPrefix(prefix string) bool {
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func setEnvField(f reflect.Value, s string) error {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(n))
+	case reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		f.Set(reflect.ValueOf(list))
+	case reflect.Map:
+		m := map[string]string{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("invalid pair %q", item)
+			}
+			m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		f.Set(reflect.ValueOf(m))
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package ulog
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigBuild(t *testing.T) {
+	dir := t.TempDir()
+	resetRegistry(t, StdLogger)
+	var cfg Config
+	data := `{
+		"level": "info",
+		"encoder": "json",
+		"time_layout": "-",
+		"outputs": ["` + filepath.Join(dir, "a.log") + `", "` + filepath.Join(dir, "logs", "b.log") + `"],
+		"rotation": {"max_size": "1MB", "max_backups": 2},
+		"modules": {"db": "debug"}
+	}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("Unmarshal(): %v", err)
+	}
+	l, closer, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build(): %v", err)
+	}
+	defer closer.Close()
+	l.Debug("hidden")
+	l.Info("info")
+	SetDefault(l)
+	Named("db").Debug("query")
+
+	for _, name := range []string{"a.log", "logs/b.log"} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("ReadFile(): %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		if len(lines) != 2 || !strings.Contains(lines[0], `"msg":"info"`) || !strings.Contains(lines[1], `"msg":"query","logger":"db"`) {
+			t.Errorf("Build(): %v got %q", name, b)
+		}
+	}
+	if level, ok := LevelFor("db"); level != LevelDebug || !ok {
+		t.Errorf("Build(): got db level %v %v, want %v true", level, ok, LevelDebug)
+	}
+}
+
+func TestConfigSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	l, closer, err := Config{Encoder: "text", Outputs: []string{path}, Sampling: &SamplingConfig{Interval: "1h", First: 2}}.Build()
+	if err != nil {
+		t.Fatalf("Build(): %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		l.Info("same")
+	}
+	if err := closer.Close(); err != nil {
+		t.Errorf("Close(): %v", err)
+	}
+	if err := closer.Close(); err == nil || !strings.Contains(err.Error(), "already closed") {
+		t.Errorf("Close(): got %v, want the file already closed", err)
+	}
+	// Standard streams are not closed.
+	_, closer, err = Config{Outputs: []string{"stdout", "stderr"}}.Build()
+	if err != nil || closer.Close() != nil || closer.Close() != nil {
+		t.Errorf("Build(): got %v, want standard streams left open", err)
+	}
+	b, _ := os.ReadFile(path)
+	if got := strings.Count(string(b), "msg=same"); got != 2 {
+		t.Errorf("Build(): got %v sampled records, want 2", got)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name  string
+		cfg   Config
+		field string
+	}{
+		{"level", Config{Level: "loud"}, "level"},
+		{"encoder", Config{Encoder: "xml"}, "encoder"},
+		{"color", Config{Color: "rainbow"}, "color"},
+		{"stacktrace", Config{Stacktrace: "x"}, "stacktrace"},
+		{"module", Config{Modules: map[string]string{"db": "x"}}, "modules.db"},
+		{"output", Config{Outputs: []string{"stdout", ""}}, "outputs[1]"},
+		{"output dir", Config{Outputs: []string{dir}}, "outputs[0]"},
+		{"max size", Config{Rotation: &RotationConfig{MaxSize: "big"}}, "rotation.max_size"},
+		{"interval", Config{Rotation: &RotationConfig{Interval: "weekly"}}, "rotation.interval"},
+		{"max age", Config{Rotation: &RotationConfig{MaxAge: "7d"}}, "rotation.max_age"},
+		{"max backups", Config{Rotation: &RotationConfig{MaxBackups: -1}}, "rotation.max_backups"},
+		{"sampling interval", Config{Sampling: &SamplingConfig{Interval: "1x"}}, "sampling.interval"},
+		{"sampling first", Config{Sampling: &SamplingConfig{First: -1}}, "sampling.first"},
+		{"sampling drops all", Config{Sampling: &SamplingConfig{Interval: "1s"}}, "sampling.first"},
+	}
+	for _, tt := range tests {
+		_, _, err := tt.cfg.Build()
+		var cerr *ConfigError
+		if !errors.As(err, &cerr) || cerr.Field != tt.field || !strings.HasPrefix(err.Error(), "ulog: config "+tt.field+": ") {
+			t.Errorf("Build(): name %v , got %v, want field %v", tt.name, err, tt.field)
+		}
+	}
+}
+
+func TestConfigLoadEnv(t *testing.T) {
+	t.Setenv("APP_LEVEL", "warn")
+	t.Setenv("APP_CALLER", "true")
+	t.Setenv("APP_OUTPUTS", "stdout, /var/log/app.log")
+	t.Setenv("APP_MODULES", "db=debug, http=error")
+	t.Setenv("APP_ROTATION_MAX_SIZE", "10MB")
+	t.Setenv("APP_ROTATION_MAX_BACKUPS", "3")
+
+	cfg := Config{Encoder: "json"}
+	if err := cfg.LoadEnv("app"); err != nil {
+		t.Fatalf("LoadEnv(): %v", err)
+	}
+	want := Config{
+		Level:    "warn",
+		Encoder:  "json",
+		Caller:   true,
+		Outputs:  []string{"stdout", "/var/log/app.log"},
+		Modules:  map[string]string{"db": "debug", "http": "error"},
+		Rotation: &RotationConfig{MaxSize: "10MB", MaxBackups: 3},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("LoadEnv(): got %+v, want %+v", cfg, want)
+	}
+
+	t.Setenv("APP_SAMPLING_FIRST", "many")
+	var cerr *ConfigError
+	if err := cfg.LoadEnv("APP"); !errors.As(err, &cerr) || cerr.Field != "APP_SAMPLING_FIRST" {
+		t.Errorf("LoadEnv(): got %v, want field APP_SAMPLING_FIRST", err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+		err  bool
+	}{
+		{"512", 512, false},
+		{"10KB", 10 << 10, false},
+		{"1.5 gb", 3 << 29, false},
+		{"100MB", 100 << 20, false},
+		{"2B", 2, false},
+		{"MB", 0, true},
+		{"-1KB", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.s)
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("parseSize(): name %v , got %v %v, want %v %v", tt.s, got, err, tt.want, tt.err)
+		}
+	}
+}
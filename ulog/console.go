@@ -0,0 +1,173 @@
+package ulog
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xbmlz/guc/utime"
+)
+
+// ConsoleConfig configures NewConsoleEncoder.
+type ConsoleConfig struct {
+	// TimeLayout is a time layout or an utime layout name,
+	// "yyyy-MM-dd HH:mm:ss.SSS" by default. OmitKey omits the time.
+	TimeLayout string
+	// CallerWidth is the width of the caller column, 24 by default.
+	CallerWidth int
+	// MessageWidth is the width of the message column, 40 by default.
+	// Fields of shorter messages start at the same column.
+	MessageWidth int
+}
+
+// Colors of the console encoder besides the level colors of the theme.
+const (
+	consoleFaint   = "\033[2m"
+	consoleRed     = "\033[31m"
+	consoleYellow  = "\033[33m"
+	consoleBlue    = "\033[34m"
+	consoleMagenta = "\033[35m"
+)
+
+// consoleEncoder writes records for reading in a terminal, in aligned
+// columns of time, level, caller and message followed by the fields.
+type consoleEncoder struct {
+	cfg   ConsoleConfig
+	theme Theme
+}
+
+// NewConsoleEncoder returns an Encoder for local development that writes
+// records in aligned columns:
+//
+//	2024-01-01 12:00:00.000 INFO  app/main.go:12           server started       addr=:8080
+//
+// Fields are key=value pairs colored by the type of the value, and values
+// spanning several lines, such as stack traces, are written indented below
+// the record. Colors follow WithColor and WithTheme.
+func NewConsoleEncoder(cfg ConsoleConfig) Encoder {
+	if cfg.TimeLayout == "" {
+		cfg.TimeLayout = "yyyy-MM-dd HH:mm:ss.SSS"
+	}
+	if cfg.CallerWidth <= 0 {
+		cfg.CallerWidth = 24
+	}
+	if cfg.MessageWidth <= 0 {
+		cfg.MessageWidth = 40
+	}
+	return consoleEncoder{cfg: cfg}
+}
+
+func (e consoleEncoder) withTheme(theme Theme) Encoder {
+	e.theme = theme
+	return e
+}
+
+func (e consoleEncoder) Encode(buf *bytes.Buffer, r *Record) error {
+	b := make([]byte, 0, 256)
+	if e.cfg.TimeLayout != OmitKey && !r.Time.IsZero() {
+		b = e.paint(b, consoleFaint, utime.Format(r.Time, e.cfg.TimeLayout))
+		b = append(b, ' ')
+	}
+	b = e.theme.colorize(b, r.Level, padRight(r.Level.String(), 5))
+	b = append(b, ' ')
+	if r.Caller != "" {
+		b = e.paint(b, consoleFaint, padRight(r.Caller, e.cfg.CallerWidth))
+		b = append(b, ' ')
+	}
+
+	var fields []Field
+	if r.Function != "" {
+		fields = append(fields, Field{Key: FunctionKey, Value: r.Function})
+	}
+	fields = flattenFields(fields, "", r.Fields)
+	if r.Stack != "" {
+		fields = append(fields, Field{Key: StackKey, Value: r.Stack})
+	}
+	var inline, multiline []Field
+	for _, f := range fields {
+		if s, ok := f.Value.(string); ok && strings.Contains(s, "\n") {
+			multiline = append(multiline, f)
+		} else {
+			inline = append(inline, f)
+		}
+	}
+
+	if len(inline) == 0 {
+		b = append(b, r.Message...)
+	} else {
+		b = append(b, padRight(r.Message, e.cfg.MessageWidth)...)
+	}
+	for _, f := range inline {
+		b = append(b, ' ')
+		b = e.paint(b, consoleFaint, f.Key+"=")
+		b = e.paint(b, valueColor(f.Value), string(appendText(nil, valueString(f.Value))))
+	}
+	b = append(b, '\n')
+	for _, f := range multiline {
+		b = append(b, "    "...)
+		b = e.paint(b, consoleFaint, f.Key+":")
+		b = append(b, '\n')
+		for _, line := range strings.Split(strings.TrimRight(f.Value.(string), "\n"), "\n") {
+			b = append(b, "        "...)
+			b = append(b, line...)
+			b = append(b, '\n')
+		}
+	}
+	buf.Write(b)
+	return nil
+}
+
+// paint appends s in color if the encoder is colored.
+func (e consoleEncoder) paint(b []byte, color, s string) []byte {
+	if e.theme == nil || color == "" {
+		return append(b, s...)
+	}
+	b = append(b, color...)
+	b = append(b, s...)
+	return append(b, colorReset...)
+}
+
+// valueColor returns the console color of a field value.
+func valueColor(v any) string {
+	switch v := v.(type) {
+	case nil, error:
+		return consoleRed
+	case string:
+		if v == MissingValue {
+			return consoleRed
+		}
+		return ""
+	case bool:
+		return consoleYellow
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64:
+		return consoleBlue
+	case time.Time, time.Duration:
+		return consoleMagenta
+	}
+	return ""
+}
+
+// flattenFields appends fields to dst, with the fields of groups under dotted keys.
+func flattenFields(dst []Field, prefix string, fields []Field) []Field {
+	for _, f := range fields {
+		key := f.Key
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if group, ok := f.Value.([]Field); ok {
+			dst = flattenFields(dst, key, group)
+			continue
+		}
+		dst = append(dst, Field{Key: key, Value: f.Value})
+	}
+	return dst
+}
+
+// padRight pads s with spaces to width runes.
+func padRight(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
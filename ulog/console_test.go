@@ -0,0 +1,54 @@
+package ulog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConsoleEncoder(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 5e6, time.UTC)
+	tests := []struct {
+		name string
+		cfg  ConsoleConfig
+		r    *Record
+		want string
+	}{
+		{"columns", ConsoleConfig{}, &Record{Time: ts, Level: LevelInfo, Message: "started", Caller: "app/main.go:12", Fields: []Field{{"addr", ":8080"}, {"tls", false}}},
+			"2024-01-01 12:00:00.005 INFO  app/main.go:12           started                                  addr=:8080 tls=false\n"},
+		{"no fields", ConsoleConfig{}, &Record{Time: ts, Level: LevelWarn, Message: "slow", Caller: "app/main.go:9"},
+			"2024-01-01 12:00:00.005 WARN  app/main.go:9            slow\n"},
+		{"layout and widths", ConsoleConfig{TimeLayout: "HH:mm:ss", CallerWidth: 8, MessageWidth: 6}, &Record{Time: ts, Level: LevelError, Message: "failed", Caller: "app/db.go:100", Fields: []Field{{"db", []Field{{"host", "db 1"}}}}},
+			"12:00:00 ERROR app/db.go:100 failed db.host=\"db 1\"\n"},
+		{"utime layout", ConsoleConfig{TimeLayout: "yyyy/MM/dd HH:mm:ss", MessageWidth: 4}, &Record{Time: ts, Level: LevelDebug, Message: "ok", Fields: []Field{{"n", 1}}},
+			"2024/01/01 12:00:00 DEBUG ok   n=1\n"},
+		{"multiline", ConsoleConfig{TimeLayout: OmitKey, MessageWidth: 1}, &Record{Level: LevelError, Message: "panic", Function: "main.run", Stack: "main.run()\n\tmain.go:10\n", Fields: []Field{{"query", "select 1\nfrom t"}}},
+			"ERROR panic func=main.run\n    query:\n        select 1\n        from t\n    stack:\n        main.run()\n        \tmain.go:10\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := NewConsoleEncoder(tt.cfg).Encode(&buf, tt.r); err != nil {
+			t.Fatalf("Encode(): %v", err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("NewConsoleEncoder(): name %v , got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConsoleEncoderColor(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(&buf, WithColor(ColorAlways), WithEncoder(NewConsoleEncoder(ConsoleConfig{TimeLayout: OmitKey, MessageWidth: 1})))
+	l.Log(LevelWarn, MessageKey, "m", "s", "x", "n", 1.5, "ok", true, "err", errors.New("bad"), "d", time.Second)
+
+	want := "\033[33mWARN \033[0m m" +
+		" \033[2ms=\033[0mx" +
+		" \033[2mn=\033[0m\033[34m1.5\033[0m" +
+		" \033[2mok=\033[0m\033[33mtrue\033[0m" +
+		" \033[2merr=\033[0m\033[31mbad\033[0m" +
+		" \033[2md=\033[0m\033[35m1s\033[0m\n"
+	if got := buf.String(); got != want {
+		t.Errorf("NewConsoleEncoder(): got %q, want %q", got, want)
+	}
+}
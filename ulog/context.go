@@ -0,0 +1,85 @@
+package ulog
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// RequestIDKey is the key of a request id extracted from a context.
+	RequestIDKey = "request_id"
+	// TraceIDKey is the key of a trace id extracted from a context.
+	TraceIDKey = "trace_id"
+	// SpanIDKey is the key of a span id extracted from a context.
+	SpanIDKey = "span_id"
+)
+
+// ContextExtractor returns alternating keys and values taken from ctx,
+// such as a request id or a trace id, to add to every record.
+type ContextExtractor func(ctx context.Context) []any
+
+var extractors struct {
+	mu  sync.RWMutex
+	fns []ContextExtractor
+}
+
+// RegisterContextExtractor registers fn to be called by FromContext and WithContext.
+func RegisterContextExtractor(fn ContextExtractor) {
+	extractors.mu.Lock()
+	defer extractors.mu.Unlock()
+	extractors.fns = append(extractors.fns, fn)
+}
+
+// ContextValue returns a ContextExtractor that adds ctx.Value(ctxKey) under
+// key if it is not nil.
+// e.g. RegisterContextExtractor(ContextValue(RequestIDKey, requestIDKey{}))
+func ContextValue(key string, ctxKey any) ContextExtractor {
+	return func(ctx context.Context) []any {
+		if v := ctx.Value(ctxKey); v != nil {
+			return []any{key, v}
+		}
+		return nil
+	}
+}
+
+// contextFields returns the fields of all registered extractors for ctx.
+func contextFields(ctx context.Context) []any {
+	if ctx == nil {
+		return nil
+	}
+	extractors.mu.RLock()
+	defer extractors.mu.RUnlock()
+	var keyvals []any
+	for _, fn := range extractors.fns {
+		keyvals = append(keyvals, fn(ctx)...)
+	}
+	return keyvals
+}
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx that carries l.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or StdLogger if there is
+// none, with the fields of the registered extractors added.
+func FromContext(ctx context.Context) Logger {
+	l := Default()
+	if ctx != nil {
+		if cl, ok := ctx.Value(loggerKey{}).(Logger); ok {
+			l = cl
+		}
+	}
+	return WithContext(ctx, l)
+}
+
+// WithContext returns l with the fields of the registered extractors for ctx added.
+func WithContext(ctx context.Context, l Logger) Logger {
+	keyvals := contextFields(ctx)
+	if len(keyvals) == 0 {
+		return l
+	}
+	return l.With(keyvals...)
+}
@@ -0,0 +1,59 @@
+package ulog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+type requestIDKey struct{}
+
+type traceKey struct{}
+
+// resetExtractors removes the registered extractors until the test ends.
+func resetExtractors(t *testing.T) {
+	extractors.mu.Lock()
+	saved := extractors.fns
+	extractors.fns = nil
+	extractors.mu.Unlock()
+	t.Cleanup(func() {
+		extractors.mu.Lock()
+		extractors.fns = saved
+		extractors.mu.Unlock()
+	})
+}
+
+func TestFromContext(t *testing.T) {
+	resetExtractors(t)
+
+	if got := FromContext(context.Background()); got != StdLogger {
+		t.Errorf("FromContext(): got %v, want StdLogger", got)
+	}
+
+	RegisterContextExtractor(ContextValue(RequestIDKey, requestIDKey{}))
+	RegisterContextExtractor(func(ctx context.Context) []any {
+		if ids, ok := ctx.Value(traceKey{}).([2]string); ok {
+			return []any{TraceIDKey, ids[0], SpanIDKey, ids[1]}
+		}
+		return nil
+	})
+
+	var buf bytes.Buffer
+	ctx := NewContext(context.Background(), NewStdLogger(&buf).With("a", 1))
+	FromContext(ctx).Info("no ids")
+	ctx = context.WithValue(ctx, requestIDKey{}, "req-1")
+	ctx = context.WithValue(ctx, traceKey{}, [2]string{"t1", "s1"})
+	FromContext(ctx).Info("ids")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []string{"no ids a=1", "ids a=1 request_id=req-1 trace_id=t1 span_id=s1"}
+	if len(lines) != len(want) {
+		t.Fatalf("FromContext(): got %q, want %q", lines, want)
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Errorf("FromContext(): got %q, want suffix %q", lines[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package ulog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xbmlz/guc/utime"
+)
+
+const (
+	// TimeKey is the default key of the record time.
+	TimeKey = "time"
+	// CallerKey is the default key of the record caller.
+	CallerKey = "caller"
+	// FunctionKey is the default key of the record function.
+	FunctionKey = "func"
+	// StackKey is the default key of the record stack trace.
+	StackKey = "stack"
+)
+
+// OmitKey is the EncoderConfig key that omits a field from the output.
+const OmitKey = "-"
+
+// Record is a single log entry passed to an Encoder.
+type Record struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	// Caller is the file:line of the log call, empty if not captured.
+	Caller string
+	// Function is the function of the log call, empty if not captured.
+	Function string
+	// Stack is the stack trace of the log call, empty if not captured.
+	Stack  string
+	Fields []Field
+}
+
+// Encoder encodes a record as a single line, including the trailing newline.
+// A zero Record.Time is omitted.
+type Encoder interface {
+	Encode(buf *bytes.Buffer, r *Record) error
+}
+
+// EncoderConfig configures the field names and time layout of an Encoder.
+// An empty key uses the default key, and OmitKey omits the field.
+type EncoderConfig struct {
+	TimeKey     string
+	LevelKey    string
+	MessageKey  string
+	CallerKey   string
+	FunctionKey string
+	StackKey    string
+	// TimeLayout is a time layout or an utime layout name, e.g. "yyyy-MM-dd HH:mm:ss".
+	TimeLayout string
+}
+
+// withDefaults returns a copy of c with empty keys set to the defaults.
+func (c EncoderConfig) withDefaults(layout string) EncoderConfig {
+	if c.TimeKey == "" {
+		c.TimeKey = TimeKey
+	}
+	if c.LevelKey == "" {
+		c.LevelKey = LevelKey
+	}
+	if c.MessageKey == "" {
+		c.MessageKey = MessageKey
+	}
+	if c.CallerKey == "" {
+		c.CallerKey = CallerKey
+	}
+	if c.FunctionKey == "" {
+		c.FunctionKey = FunctionKey
+	}
+	if c.StackKey == "" {
+		c.StackKey = StackKey
+	}
+	if c.TimeLayout == "" {
+		c.TimeLayout = layout
+	}
+	return c
+}
+
+type jsonEncoder struct {
+	cfg EncoderConfig
+}
+
+// NewJSONEncoder returns an Encoder that writes one JSON object per line.
+// Keys are written in a stable order: time, level, message, caller, function,
+// stack, then the fields in the order they were added.
+func NewJSONEncoder(cfg EncoderConfig) Encoder {
+	return &jsonEncoder{cfg: cfg.withDefaults(time.RFC3339Nano)}
+}
+
+func (e *jsonEncoder) Encode(buf *bytes.Buffer, r *Record) error {
+	b := make([]byte, 0, 256)
+	b = append(b, '{')
+	first := true
+	key := func(k string) {
+		if !first {
+			b = append(b, ',')
+		}
+		first = false
+		b = appendJSONString(b, k)
+		b = append(b, ':')
+	}
+	if e.cfg.TimeKey != OmitKey && !r.Time.IsZero() {
+		key(e.cfg.TimeKey)
+		b = appendJSONString(b, utime.Format(r.Time, e.cfg.TimeLayout))
+	}
+	if e.cfg.LevelKey != OmitKey {
+		key(e.cfg.LevelKey)
+		b = appendJSONString(b, r.Level.String())
+	}
+	if e.cfg.MessageKey != OmitKey {
+		key(e.cfg.MessageKey)
+		b = appendJSONString(b, r.Message)
+	}
+	if e.cfg.CallerKey != OmitKey && r.Caller != "" {
+		key(e.cfg.CallerKey)
+		b = appendJSONString(b, r.Caller)
+	}
+	if e.cfg.FunctionKey != OmitKey && r.Function != "" {
+		key(e.cfg.FunctionKey)
+		b = appendJSONString(b, r.Function)
+	}
+	if e.cfg.StackKey != OmitKey && r.Stack != "" {
+		key(e.cfg.StackKey)
+		b = appendJSONString(b, r.Stack)
+	}
+	for _, f := range r.Fields {
+		key(f.Key)
+		b = appendJSONValue(b, f.Value)
+	}
+	b = append(b, '}', '\n')
+	buf.Write(b)
+	return nil
+}
+
+// appendJSONValue appends v as a JSON value. Groups are written as nested
+// objects and errors as their message.
+func appendJSONValue(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "null"...)
+	case string:
+		return appendJSONString(b, v)
+	case []Field:
+		b = append(b, '{')
+		for i, f := range v {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendJSONString(b, f.Key)
+			b = append(b, ':')
+			b = appendJSONValue(b, f.Value)
+		}
+		return append(b, '}')
+	case json.Marshaler:
+	case error:
+		return appendJSONString(b, safeString(v, "Error", v.Error))
+	case bool:
+		return strconv.AppendBool(b, v)
+	case int:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(b, v, 10)
+	case uint64:
+		return strconv.AppendUint(b, v, 10)
+	case time.Duration:
+		return appendJSONString(b, v.String())
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return appendJSONString(b, valueString(v))
+	}
+	return append(b, data...)
+}
+
+// appendJSONString appends s as a JSON string without escaping HTML characters.
+func appendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				b = append(b, '\\', c)
+			case c == '\n':
+				b = append(b, '\\', 'n')
+			case c == '\r':
+				b = append(b, '\\', 'r')
+			case c == '\t':
+				b = append(b, '\\', 't')
+			case c < 0x20:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				b = append(b, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, "\ufffd"...)
+		} else {
+			b = append(b, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(b, '"')
+}
+
+type textEncoder struct {
+	cfg EncoderConfig
+}
+
+// NewTextEncoder returns an Encoder that writes logfmt lines of key=value pairs.
+// Groups are flattened into dotted keys, and the stack trace is quoted so
+// every record stays on one line.
+func NewTextEncoder(cfg EncoderConfig) Encoder {
+	return &textEncoder{cfg: cfg.withDefaults("2006-01-02T15:04:05.000Z07:00")}
+}
+
+func (e *textEncoder) Encode(buf *bytes.Buffer, r *Record) error {
+	b := make([]byte, 0, 256)
+	var fields []Field
+	if e.cfg.TimeKey != OmitKey && !r.Time.IsZero() {
+		fields = append(fields, Field{Key: e.cfg.TimeKey, Value: utime.Format(r.Time, e.cfg.TimeLayout)})
+	}
+	if e.cfg.LevelKey != OmitKey {
+		fields = append(fields, Field{Key: e.cfg.LevelKey, Value: r.Level.String()})
+	}
+	if e.cfg.MessageKey != OmitKey {
+		fields = append(fields, Field{Key: e.cfg.MessageKey, Value: r.Message})
+	}
+	if e.cfg.CallerKey != OmitKey && r.Caller != "" {
+		fields = append(fields, Field{Key: e.cfg.CallerKey, Value: r.Caller})
+	}
+	if e.cfg.FunctionKey != OmitKey && r.Function != "" {
+		fields = append(fields, Field{Key: e.cfg.FunctionKey, Value: r.Function})
+	}
+	if e.cfg.StackKey != OmitKey && r.Stack != "" {
+		fields = append(fields, Field{Key: e.cfg.StackKey, Value: r.Stack})
+	}
+	b = appendFields(b, "", fields)
+	b = appendFields(b, "", r.Fields)
+	b = append(b, '\n')
+	buf.Write(b)
+	return nil
+}
+
+// stdEncoder writes the classic "[INFO ] 2006/01/02 15:04:05 msg key=value" lines.
+// The caller and function are written before the message, and the stack
+// trace on the lines after it.
+type stdEncoder struct {
+	theme Theme
+}
+
+func (e stdEncoder) withTheme(theme Theme) Encoder {
+	return stdEncoder{theme: theme}
+}
+
+func (e stdEncoder) Encode(buf *bytes.Buffer, r *Record) error {
+	b := make([]byte, 0, 256)
+	b = e.theme.colorize(b, r.Level, levelTag(r.Level))
+	b = append(b, ' ')
+	if !r.Time.IsZero() {
+		b = r.Time.AppendFormat(b, "2006/01/02 15:04:05")
+		b = append(b, ' ')
+	}
+	if r.Caller != "" {
+		b = append(b, r.Caller...)
+		b = append(b, ' ')
+	}
+	if r.Function != "" {
+		b = append(b, r.Function...)
+		b = append(b, ' ')
+	}
+	b = append(b, r.Message...)
+	b = appendFields(b, "", r.Fields)
+	b = append(b, '\n')
+	if r.Stack != "" {
+		b = append(b, r.Stack...)
+		b = append(b, '\n')
+	}
+	buf.Write(b)
+	return nil
+}
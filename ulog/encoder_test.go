@@ -0,0 +1,91 @@
+package ulog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testRecord = Record{
+	Time:    time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC),
+	Level:   LevelInfo,
+	Message: "hello \"world\"",
+	Fields: Fields(
+		"a", 1,
+		"err", errors.New("boom"),
+		"m", map[string]any{"y": true, "x": "<b>"},
+		"d", time.Second,
+	),
+}
+
+func TestJSONEncoder(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  EncoderConfig
+		want string
+	}{
+		{"default", EncoderConfig{}, `{"time":"2024-01-02T03:04:05.006Z","level":"INFO","msg":"hello \"world\"","a":1,"err":"boom","m":{"x":"<b>","y":true},"d":"1s"}` + "\n"},
+		{"keys", EncoderConfig{TimeKey: "ts", LevelKey: "severity", MessageKey: "message", TimeLayout: "yyyy-MM-dd HH:mm:ss"}, `{"ts":"2024-01-02 03:04:05","severity":"INFO","message":"hello \"world\"","a":1,"err":"boom","m":{"x":"<b>","y":true},"d":"1s"}` + "\n"},
+		{"omit", EncoderConfig{TimeKey: OmitKey, LevelKey: OmitKey}, `{"msg":"hello \"world\"","a":1,"err":"boom","m":{"x":"<b>","y":true},"d":"1s"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewJSONEncoder(tt.cfg).Encode(&buf, &testRecord); err != nil {
+				t.Fatalf("Encode(): %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Encode(): name %v , got %v, want %v", tt.name, got, tt.want)
+			}
+			if !json.Valid(buf.Bytes()) {
+				t.Errorf("Encode(): name %v , invalid json %v", tt.name, buf.String())
+			}
+		})
+	}
+}
+
+func TestJSONEncoderNilError(t *testing.T) {
+	var buf bytes.Buffer
+	r := Record{Level: LevelInfo, Message: "m", Fields: Fields("err", (*nilError)(nil), "s", (*panicStringer)(nil))}
+	if err := NewJSONEncoder(EncoderConfig{TimeKey: OmitKey}).Encode(&buf, &r); err != nil {
+		t.Fatalf("Encode(): %v", err)
+	}
+	if got, want := buf.String(), `{"level":"INFO","msg":"m","err":"<nil>","s":null}`+"\n"; got != want {
+		t.Errorf("Encode(): got %v, want %v", got, want)
+	}
+}
+
+func TestTextEncoder(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  EncoderConfig
+		want string
+	}{
+		{"default", EncoderConfig{}, `time=2024-01-02T03:04:05.006Z level=INFO msg="hello \"world\"" a=1 err=boom m.x=<b> m.y=true d=1s` + "\n"},
+		{"omit", EncoderConfig{TimeKey: OmitKey, MessageKey: "message"}, `level=INFO message="hello \"world\"" a=1 err=boom m.x=<b> m.y=true d=1s` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewTextEncoder(tt.cfg).Encode(&buf, &testRecord); err != nil {
+				t.Fatalf("Encode(): %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Encode(): name %v , got %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(&buf, WithEncoder(NewJSONEncoder(EncoderConfig{TimeKey: OmitKey})))
+	l.With("a", 1).Info("hello")
+	l.Log(LevelWarn, MessageKey, "warn", "b", 2)
+	want := `{"level":"INFO","msg":"hello","a":1}` + "\n" + `{"level":"WARN","msg":"warn","b":2}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WithEncoder(): got %v, want %v", got, want)
+	}
+}
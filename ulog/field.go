@@ -0,0 +1,197 @@
+package ulog
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MessageKey is the key of the log message.
+	MessageKey = "msg"
+	// BadKey is the key used for a value whose key is not a string.
+	BadKey = "!BADKEY"
+	// MissingValue is the value used for a key without a value.
+	MissingValue = "!MISSING"
+)
+
+// Field is a key-value pair attached to a log record.
+// A Value of type []Field is a group of nested fields.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Fields converts alternating keys and values into fields.
+// A Field may also be passed as a single element.
+// A key that is not a string is stored under BadKey, and a trailing key
+// without a value gets MissingValue.
+// Maps with string keys are converted into groups sorted by key.
+// e.g. Fields("a", 1, "b") => [{a 1} {b !MISSING}]
+func Fields(keyvals ...any) []Field {
+	if len(keyvals) == 0 {
+		return nil
+	}
+	fields := make([]Field, 0, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i++ {
+		switch k := keyvals[i].(type) {
+		case Field:
+			fields = append(fields, Field{Key: k.Key, Value: fieldValue(k.Value)})
+		case string:
+			if i+1 == len(keyvals) {
+				fields = append(fields, Field{Key: k, Value: MissingValue})
+				break
+			}
+			i++
+			fields = append(fields, Field{Key: k, Value: fieldValue(keyvals[i])})
+		default:
+			fields = append(fields, Field{Key: BadKey, Value: fieldValue(k)})
+		}
+	}
+	return fields
+}
+
+// Group returns a field that nests the given keys and values under key.
+// e.g. Group("http", "method", "GET") => {http [{method GET}]}
+func Group(key string, keyvals ...any) Field {
+	return Field{Key: key, Value: Fields(keyvals...)}
+}
+
+// fieldValue normalizes maps with string keys into sorted groups.
+func fieldValue(v any) any {
+	switch v := v.(type) {
+	case nil, string, error, []Field:
+		return v
+	case map[string]any:
+		fields := make([]Field, 0, len(v))
+		for k, e := range v {
+			fields = append(fields, Field{Key: k, Value: fieldValue(e)})
+		}
+		sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+		return fields
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return v
+	}
+	fields := make([]Field, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		fields = append(fields, Field{Key: iter.Key().String(), Value: fieldValue(iter.Value().Interface())})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	return fields
+}
+
+// splitMessage removes the first MessageKey field and returns its value as the message.
+func splitMessage(fields []Field) (string, []Field) {
+	for i, f := range fields {
+		if f.Key == MessageKey {
+			rest := make([]Field, 0, len(fields)-1)
+			rest = append(rest, fields[:i]...)
+			rest = append(rest, fields[i+1:]...)
+			return valueString(f.Value), rest
+		}
+	}
+	return "", fields
+}
+
+// appendFields appends fields in logfmt style, flattening groups with dotted keys.
+func appendFields(b []byte, prefix string, fields []Field) []byte {
+	for _, f := range fields {
+		key := f.Key
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if group, ok := f.Value.([]Field); ok {
+			b = appendFields(b, key, group)
+			continue
+		}
+		if len(b) > 0 && b[len(b)-1] != ' ' {
+			b = append(b, ' ')
+		}
+		b = appendText(b, key)
+		b = append(b, '=')
+		b = appendText(b, valueString(f.Value))
+	}
+	return b
+}
+
+// valueString returns the text form of a field value.
+func valueString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		return v
+	case Redacter:
+		return valueString(redacted(v))
+	case error:
+		return safeString(v, "Error", v.Error)
+	case fmt.Stringer:
+		return safeString(v, "String", v.String)
+	}
+	return fmt.Sprint(v)
+}
+
+// safeString calls fn, the method of v named method, and returns what fmt
+// prints if it panics, such as for a nil pointer with a value method.
+func safeString(v any, method string, fn func() string) (s string) {
+	defer func() {
+		if p := recover(); p != nil {
+			s = panicText(v, method, p)
+		}
+	}()
+	return fn()
+}
+
+// redacted returns the Redacted value of r, guarded like safeString.
+func redacted(r Redacter) (v any) {
+	defer func() {
+		if p := recover(); p != nil {
+			v = panicText(r, "Redacted", p)
+		}
+	}()
+	return r.Redacted()
+}
+
+// panicText returns the text fmt prints for v when its method panics with p.
+func panicText(v any, method string, p any) string {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%%!v(PANIC=%s method: %v)", method, p)
+}
+
+// appendText appends s, quoting it if it is empty or contains spaces, quotes, '=' or control characters.
+func appendText(b []byte, s string) []byte {
+	if needsQuote(s) {
+		return strconv.AppendQuote(b, s)
+	}
+	return append(b, s...)
+}
+
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// sprint formats v like fmt.Sprintln without the trailing newline.
+func sprint(v ...any) string {
+	return strings.TrimSuffix(fmt.Sprintln(redactArgs(v)...), "\n")
+}
+
+// sprintf formats v like fmt.Sprintf.
+func sprintf(format string, v ...any) string {
+	return fmt.Sprintf(format, redactArgs(v)...)
+}
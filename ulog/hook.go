@@ -0,0 +1,75 @@
+package ulog
+
+import (
+	"fmt"
+	"os"
+)
+
+// Hook is called with the records of a logger at or above its level, after
+// redaction and before encoding. It may add fields to the record, which are
+// redacted once the hooks have run, but must not keep it after Fire returns. Fire is called from the goroutines that log,
+// so it must be safe for concurrent use.
+type Hook interface {
+	Fire(r *Record) error
+}
+
+// HookFunc is a function used as a Hook.
+type HookFunc func(r *Record) error
+
+// Fire calls f(r).
+func (f HookFunc) Fire(r *Record) error {
+	return f(r)
+}
+
+type hookEntry struct {
+	level Level
+	hook  Hook
+}
+
+// WithHook adds a hook called with the records at or above level. Hooks run
+// in the order they were added. An error or panic of a hook is passed to the
+// error handler and the record is still written.
+// e.g. WithHook(LevelError, HookFunc(func(r *Record) error { return alert(r.Message) }))
+func WithHook(level Level, hook Hook) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, hookEntry{level: level, hook: hook})
+	}
+}
+
+// WithErrorHandler sets the function called with the errors of hooks,
+// encoders and writers. The default prints them to stderr.
+func WithErrorHandler(handle func(err error)) Option {
+	return func(o *options) {
+		o.handleError = handle
+	}
+}
+
+// printError is the default error handler.
+func printError(err error) {
+	fmt.Fprintf(os.Stderr, "%v\n", err)
+}
+
+// fireHooks calls the hooks for r and reports their errors.
+func (c *core) fireHooks(r *Record) {
+	for _, h := range c.hooks {
+		if r.Level < h.level {
+			continue
+		}
+		if err := fireHook(h.hook, r); err != nil {
+			c.handleError(err)
+		}
+	}
+}
+
+// fireHook calls hook, turning a panic into an error.
+func fireHook(hook Hook, r *Record) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("ulog: hook %T panicked: %v", hook, p)
+		}
+	}()
+	if err := hook.Fire(r); err != nil {
+		return fmt.Errorf("ulog: hook %T: %w", hook, err)
+	}
+	return nil
+}
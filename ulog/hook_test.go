@@ -0,0 +1,134 @@
+package ulog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// levelCounter counts records per level.
+type levelCounter struct {
+	counts [numLevels]uint64
+}
+
+func (c *levelCounter) Fire(r *Record) error {
+	atomic.AddUint64(&c.counts[levelIndex(r.Level)], 1)
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	var buf bytes.Buffer
+	counter := &levelCounter{}
+	var alerts []string
+	var mu sync.Mutex
+	l := NewStdLogger(&buf,
+		WithEncoder(NewTextEncoder(EncoderConfig{TimeKey: OmitKey})),
+		WithRedaction(RedactConfig{Keys: []string{"password"}}),
+		WithHook(LevelTrace, counter),
+		WithHook(LevelInfo, HookFunc(func(r *Record) error {
+			r.Fields = append(r.Fields, Field{Key: "host", Value: "web1"})
+			return nil
+		})),
+		WithHook(LevelError, HookFunc(func(r *Record) error {
+			mu.Lock()
+			defer mu.Unlock()
+			alerts = append(alerts, r.Message+" "+valueString(r.Fields[0].Value))
+			return nil
+		})),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Debug("debug")
+			l.Info("info")
+			l.Log(LevelError, MessageKey, "error", "password", "hunter2")
+		}()
+	}
+	wg.Wait()
+
+	tests := []struct {
+		level Level
+		want  uint64
+	}{
+		{LevelDebug, 10},
+		{LevelInfo, 10},
+		{LevelWarn, 0},
+		{LevelError, 10},
+	}
+	for _, tt := range tests {
+		if got := atomic.LoadUint64(&counter.counts[levelIndex(tt.level)]); got != tt.want {
+			t.Errorf("WithHook(): name %v , got %v, want %v", tt.level, got, tt.want)
+		}
+	}
+	if len(alerts) != 10 || alerts[0] != "error ***" {
+		t.Errorf("WithHook(): got alerts %v", alerts)
+	}
+	out := buf.String()
+	if strings.Count(out, "level=DEBUG msg=debug\n") != 10 || strings.Count(out, "level=ERROR msg=error password=*** host=web1\n") != 10 {
+		t.Errorf("WithHook(): got %v", out)
+	}
+}
+
+func TestHookRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	var seen string
+	l := NewStdLogger(&buf,
+		WithEncoder(NewTextEncoder(EncoderConfig{TimeKey: OmitKey})),
+		WithRedaction(RedactConfig{Keys: []string{"password"}}),
+		WithHook(LevelInfo, HookFunc(func(r *Record) error {
+			r.Fields = append(r.Fields, Field{Key: "password", Value: "leak"}, Field{Key: "token", Value: secret("abc")})
+			return nil
+		})),
+		WithHook(LevelInfo, HookFunc(func(r *Record) error {
+			seen = valueString(r.Fields[0].Value)
+			return nil
+		})),
+	)
+	l.Log(LevelInfo, MessageKey, "login", "password", "hunter2")
+	if got, want := buf.String(), "level=INFO msg=login password=*** password=*** token=secret(a...)\n"; got != want {
+		t.Errorf("WithHook(): got %v, want %v", got, want)
+	}
+	if seen != RedactedMask {
+		t.Errorf("WithHook(): hook got %v, want %v", seen, RedactedMask)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestHookErrors(t *testing.T) {
+	var buf bytes.Buffer
+	var errs []string
+	handle := WithErrorHandler(func(err error) { errs = append(errs, err.Error()) })
+	l := NewStdLogger(&buf,
+		WithEncoder(NewTextEncoder(EncoderConfig{TimeKey: OmitKey})),
+		WithHook(LevelInfo, HookFunc(func(r *Record) error { return errors.New("alert channel closed") })),
+		WithHook(LevelError, HookFunc(func(r *Record) error { panic("boom") })),
+		handle,
+	)
+	l.Info("info")
+	l.Error("error")
+	NewStdLogger(failingWriter{}, handle).Info("lost")
+
+	want := []string{
+		"ulog: hook ulog.HookFunc: alert channel closed",
+		"ulog: hook ulog.HookFunc: alert channel closed",
+		"ulog: hook ulog.HookFunc panicked: boom",
+		"ulog: write record: disk full",
+	}
+	if strings.Join(errs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("WithErrorHandler(): got %q, want %q", errs, want)
+	}
+	if got := buf.String(); got != "level=INFO msg=info\nlevel=ERROR msg=error\n" {
+		t.Errorf("WithHook(): got %v", got)
+	}
+}
@@ -0,0 +1,118 @@
+package ulog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// levelState is the body of the responses of LevelHandler in JSON.
+type levelState struct {
+	Logger   string           `json:"logger,omitempty"`
+	Level    Level            `json:"level"`
+	Override bool             `json:"override,omitempty"`
+	Loggers  map[string]Level `json:"loggers,omitempty"`
+}
+
+// LevelHandler returns an http.Handler that reads and changes the level of
+// the default logger, or of the named logger given by the "logger" query
+// parameter.
+//
+//	GET               returns the level, and the levels of the named loggers for the default logger
+//	PUT               sets the level from a JSON body {"level":"debug"} or a plain text body debug
+//	DELETE ?logger=db removes the level set for a named logger
+//
+// Responses are JSON, or plain text if the Accept header asks for text/plain.
+// e.g. http.Handle("/log/level", ulog.LevelHandler())
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(serveLevel)
+}
+
+func serveLevel(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get(LoggerKey)
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		level, err := readLevel(r)
+		if err != nil {
+			writeLevelError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if name == "" {
+			Default().SetLevel(level)
+		} else {
+			SetLevelFor(name, level)
+		}
+	case http.MethodDelete:
+		if name == "" {
+			writeLevelError(w, r, http.StatusBadRequest, fmt.Errorf("ulog: missing %s parameter", LoggerKey))
+			return
+		}
+		ResetLevelFor(name)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		writeLevelError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("ulog: method %s not allowed", r.Method))
+		return
+	}
+
+	state := levelState{Logger: name}
+	if name == "" {
+		state.Level = loggerLevel(Default())
+		for _, n := range Names() {
+			if state.Loggers == nil {
+				state.Loggers = map[string]Level{}
+			}
+			state.Loggers[n], _ = LevelFor(n)
+		}
+	} else {
+		state.Level, state.Override = LevelFor(name)
+	}
+	if acceptsText(r) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		text, _ := state.Level.MarshalText()
+		fmt.Fprintf(w, "%s\n", text)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(state)
+}
+
+// readLevel reads the level from a JSON or plain text request body.
+func readLevel(r *http.Request) (Level, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<10))
+	if err != nil {
+		return 0, err
+	}
+	var level Level
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") || strings.HasPrefix(strings.TrimSpace(string(body)), "{") {
+		var req struct {
+			Level *Level `json:"level"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return 0, err
+		}
+		if req.Level == nil {
+			return 0, errors.New("ulog: missing level")
+		}
+		return *req.Level, nil
+	}
+	err = level.UnmarshalText([]byte(strings.TrimSpace(string(body))))
+	return level, err
+}
+
+func acceptsText(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/plain")
+}
+
+func writeLevelError(w http.ResponseWriter, r *http.Request, code int, err error) {
+	if acceptsText(r) {
+		http.Error(w, err.Error(), code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,56 @@
+package ulog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLevelHandler(t *testing.T) {
+	resetRegistry(t, NewStdLogger(&bytes.Buffer{}, WithLevel(LevelInfo)))
+	Named("db")
+	h := LevelHandler()
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		accept string
+		ctype  string
+		body   string
+		code   int
+		want   string
+	}{
+		{"get", http.MethodGet, "/", "", "", "", http.StatusOK, `{"level":"info","loggers":{"db":"info"}}`},
+		{"get text", http.MethodGet, "/", "text/plain", "", "", http.StatusOK, "info"},
+		{"put json", http.MethodPut, "/", "", "application/json", `{"level":"warn"}`, http.StatusOK, `{"level":"warn","loggers":{"db":"warn"}}`},
+		{"put text", http.MethodPut, "/?logger=db", "text/plain", "text/plain", "DEBUG\n", http.StatusOK, "debug"},
+		{"get named", http.MethodGet, "/?logger=db", "", "", "", http.StatusOK, `{"logger":"db","level":"debug","override":true}`},
+		{"get child", http.MethodGet, "/?logger=db.pool", "", "", "", http.StatusOK, `{"logger":"db.pool","level":"debug","override":true}`},
+		{"delete", http.MethodDelete, "/?logger=db", "", "", "", http.StatusOK, `{"logger":"db","level":"warn"}`},
+		{"delete default", http.MethodDelete, "/", "", "", "", http.StatusBadRequest, `{"error":"ulog: missing logger parameter"}`},
+		{"bad level", http.MethodPut, "/", "text/plain", "", "loud", http.StatusBadRequest, `ulog: unknown level "loud"`},
+		{"missing level", http.MethodPut, "/", "", "application/json", `{}`, http.StatusBadRequest, `{"error":"ulog: missing level"}`},
+		{"bad method", http.MethodPost, "/", "", "", "", http.StatusMethodNotAllowed, `{"error":"ulog: method POST not allowed"}`},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		if tt.ctype != "" {
+			r.Header.Set("Content-Type", tt.ctype)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if got := strings.TrimSpace(w.Body.String()); w.Code != tt.code || got != tt.want {
+			t.Errorf("LevelHandler(): name %v , got %v %v, want %v %v", tt.name, w.Code, got, tt.code, tt.want)
+		}
+	}
+
+	if got := loggerLevel(Default()); got != LevelWarn {
+		t.Errorf("LevelHandler(): got default level %v, want %v", got, LevelWarn)
+	}
+}
@@ -0,0 +1,202 @@
+//go:build linux
+
+package ulog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// JournalSocket is the socket of the native protocol of the systemd journal.
+const JournalSocket = "/run/systemd/journal/socket"
+
+// JournalConfig configures NewJournalEncoder.
+type JournalConfig struct {
+	// Identifier is the SYSLOG_IDENTIFIER of the entries, the base name of the program by default.
+	Identifier string
+}
+
+// journalEncoder writes records in the native protocol of the systemd journal.
+type journalEncoder struct {
+	cfg JournalConfig
+}
+
+// NewJournalEncoder returns an Encoder that writes records as journal entries
+// for a JournalWriter. The level is the PRIORITY, the caller is CODE_FILE and
+// CODE_LINE, and fields are upper case with groups joined by "_".
+// e.g. NewStdLogger(w, WithEncoder(NewJournalEncoder(JournalConfig{})))
+func NewJournalEncoder(cfg JournalConfig) Encoder {
+	if cfg.Identifier == "" {
+		cfg.Identifier = filepath.Base(os.Args[0])
+	}
+	return &journalEncoder{cfg: cfg}
+}
+
+func (e *journalEncoder) Encode(buf *bytes.Buffer, r *Record) error {
+	b := make([]byte, 0, 256)
+	b = appendJournalField(b, "MESSAGE", r.Message)
+	b = appendJournalField(b, "PRIORITY", strconv.Itoa(int(SyslogSeverity(r.Level))))
+	b = appendJournalField(b, "SYSLOG_IDENTIFIER", e.cfg.Identifier)
+	if r.Caller != "" {
+		file, line := r.Caller, ""
+		if i := strings.LastIndexByte(file, ':'); i >= 0 {
+			file, line = file[:i], file[i+1:]
+		}
+		b = appendJournalField(b, "CODE_FILE", file)
+		if line != "" {
+			b = appendJournalField(b, "CODE_LINE", line)
+		}
+	}
+	if r.Function != "" {
+		b = appendJournalField(b, "CODE_FUNC", r.Function)
+	}
+	if r.Stack != "" {
+		b = appendJournalField(b, "STACK", r.Stack)
+	}
+	b = appendJournalFields(b, "", r.Fields)
+	buf.Write(b)
+	return nil
+}
+
+// appendJournalFields appends fields, flattening groups with "_".
+func appendJournalFields(b []byte, prefix string, fields []Field) []byte {
+	for _, f := range fields {
+		key := f.Key
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		if group, ok := f.Value.([]Field); ok {
+			b = appendJournalFields(b, key, group)
+			continue
+		}
+		if name := journalFieldName(key); name != "" {
+			b = appendJournalField(b, name, valueString(f.Value))
+		}
+	}
+	return b
+}
+
+// journalFieldName returns key as a journal field name of upper case
+// letters, digits and underscores, not starting with an underscore or a
+// digit, empty if none is left.
+func journalFieldName(key string) string {
+	name := make([]byte, 0, len(key))
+	for i := 0; i < len(key) && len(name) < 64; i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' || c == '_':
+			if len(name) == 0 {
+				continue
+			}
+		default:
+			if len(name) == 0 {
+				continue
+			}
+			c = '_'
+		}
+		name = append(name, c)
+	}
+	return string(name)
+}
+
+// appendJournalField appends KEY=value, or the binary form if value spans lines.
+func appendJournalField(b []byte, key, value string) []byte {
+	b = append(b, key...)
+	if strings.IndexByte(value, '\n') < 0 {
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+	b = append(b, '\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	b = append(b, size[:]...)
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// JournalWriter is an io.Writer that sends each write as one entry to the
+// systemd journal. Entries too large for a datagram are passed in a
+// temporary file. It is safe for concurrent use.
+type JournalWriter struct {
+	addr *net.UnixAddr
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// NewJournalWriter returns a JournalWriter that sends to the journal socket
+// at path, JournalSocket if empty.
+func NewJournalWriter(path string) (*JournalWriter, error) {
+	if path == "" {
+		path = JournalSocket
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	// The socket is not connected, so descriptors can be sent with WriteMsgUnix.
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &JournalWriter{addr: &net.UnixAddr{Name: path, Net: "unixgram"}, conn: conn}, nil
+}
+
+// Write sends p as one journal entry.
+func (w *JournalWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return 0, ErrClosed
+	}
+	_, err := w.conn.WriteToUnix(p, w.addr)
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		err = w.sendFile(p)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// sendFile writes p to an unlinked temporary file and sends its descriptor.
+func (w *JournalWriter) sendFile(p []byte) error {
+	f, err := os.CreateTemp("/dev/shm", "ulog-journal-")
+	if err != nil {
+		if f, err = os.CreateTemp("", "ulog-journal-"); err != nil {
+			return err
+		}
+	}
+	defer f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+	if _, err := f.Write(p); err != nil {
+		return err
+	}
+	_, _, err = w.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), w.addr)
+	return err
+}
+
+// Close closes the socket.
+func (w *JournalWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,98 @@
+//go:build linux
+
+package ulog
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestJournalEncoder(t *testing.T) {
+	r := &Record{
+		Level:    LevelError,
+		Message:  "failed",
+		Caller:   "app/main.go:10",
+		Function: "main.run",
+		Fields:   []Field{{"user", []Field{{"id", 42}}}, {"_secret", "x"}, {"2fa", true}, {"err", "line 1\nline 2"}, {"-", "skipped"}},
+	}
+	var buf bytes.Buffer
+	if err := NewJournalEncoder(JournalConfig{Identifier: "app"}).Encode(&buf, r); err != nil {
+		t.Fatalf("Encode(): %v", err)
+	}
+	want := "MESSAGE=failed\nPRIORITY=3\nSYSLOG_IDENTIFIER=app\nCODE_FILE=app/main.go\nCODE_LINE=10\nCODE_FUNC=main.run\n" +
+		"USER_ID=42\nSECRET=x\nFA=true\nERR\n\x0d\x00\x00\x00\x00\x00\x00\x00line 1\nline 2\n"
+	if got := buf.String(); got != want {
+		t.Errorf("NewJournalEncoder(): got %q, want %q", got, want)
+	}
+}
+
+func listenJournal(t *testing.T) (*net.UnixConn, *JournalWriter) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	w, err := NewJournalWriter(path)
+	if err != nil {
+		t.Fatalf("NewJournalWriter(): %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn, w
+}
+
+func TestJournalWriter(t *testing.T) {
+	conn, w := listenJournal(t)
+	l := NewStdLogger(w, WithEncoder(NewJournalEncoder(JournalConfig{Identifier: "app"})))
+	l.Warn("disk low")
+
+	p := make([]byte, 1024)
+	n, err := conn.Read(p)
+	if err != nil {
+		t.Fatalf("Read(): %v", err)
+	}
+	if want := "MESSAGE=disk low\nPRIORITY=4\nSYSLOG_IDENTIFIER=app\n"; string(p[:n]) != want {
+		t.Errorf("JournalWriter: got %q, want %q", p[:n], want)
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte("late\n")); err != ErrClosed {
+		t.Errorf("Write(): got %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestJournalWriterLarge(t *testing.T) {
+	conn, w := listenJournal(t)
+	msg := []byte("MESSAGE=" + strings.Repeat("x", 4<<20) + "\n")
+	if _, err := w.Write(msg); err != nil {
+		t.Fatalf("Write(): %v", err)
+	}
+
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := conn.ReadMsgUnix(make([]byte, 16), oob)
+	if err != nil {
+		t.Fatalf("ReadMsgUnix(): %v", err)
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("ParseSocketControlMessage(): got %v %v", msgs, err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("ParseUnixRights(): got %v %v", fds, err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "journal")
+	defer f.Close()
+	got, err := io.ReadAll(io.NewSectionReader(f, 0, 1<<30))
+	if err != nil || !bytes.Equal(got, msg) {
+		t.Errorf("JournalWriter: got %v bytes and %v, want %v bytes", len(got), err, len(msg))
+	}
+}
@@ -0,0 +1,93 @@
+package ulog
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is a logger level.
+type Level int8
+
+const (
+	// LevelTrace is logger trace level.
+	LevelTrace Level = iota - 1
+	// LevelDebug is logger debug level.
+	LevelDebug
+	// LevelInfo is logger info level.
+	LevelInfo
+	// LevelWarn is logger warn level.
+	LevelWarn
+	// LevelError is logger error level.
+	LevelError
+	// LevelPanic is logger panic level. Panic and Panicf panic after logging.
+	LevelPanic
+	// LevelFatal is logger fatal level. Fatal and Fatalf exit after logging.
+	LevelFatal
+)
+
+var levelNames = map[Level]string{
+	LevelTrace: "TRACE",
+	LevelDebug: "DEBUG",
+	LevelInfo:  "INFO",
+	LevelWarn:  "WARN",
+	LevelError: "ERROR",
+	LevelPanic: "PANIC",
+	LevelFatal: "FATAL",
+}
+
+// String returns the upper-case name of the level, e.g. "INFO".
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("LEVEL(%d)", int8(l))
+}
+
+// ParseLevel parses a level name case-insensitively.
+// e.g. ParseLevel("warn") => LevelWarn, ParseLevel("WARNING") => LevelWarn
+func ParseLevel(s string) (Level, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if name == "WARNING" {
+		return LevelWarn, nil
+	}
+	for level, n := range levelNames {
+		if n == name {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("ulog: unknown level %q", s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(l.String())), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseLevel.
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// levelTag returns the level name padded to 5 characters in brackets, e.g. "[INFO ]".
+func levelTag(level Level) string {
+	return fmt.Sprintf("[%-5s]", level.String())
+}
+
+// levelVar is a Level that is safe to read and change concurrently.
+type levelVar struct {
+	v int32
+}
+
+func (v *levelVar) Load() Level {
+	return Level(atomic.LoadInt32(&v.v))
+}
+
+func (v *levelVar) Store(level Level) {
+	atomic.StoreInt32(&v.v, int32(level))
+}
@@ -0,0 +1,69 @@
+package ulog
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLevelString(t *testing.T) {
+	tests := []struct {
+		name  string
+		level Level
+		want  string
+	}{
+		{"trace", LevelTrace, "TRACE"},
+		{"debug", LevelDebug, "DEBUG"},
+		{"info", LevelInfo, "INFO"},
+		{"warn", LevelWarn, "WARN"},
+		{"error", LevelError, "ERROR"},
+		{"panic", LevelPanic, "PANIC"},
+		{"fatal", LevelFatal, "FATAL"},
+		{"unknown", Level(42), "LEVEL(42)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.level.String(); got != tt.want {
+				t.Errorf("String(): name %v , got %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Level
+		wantErr bool
+	}{
+		{"lower", "debug", LevelDebug, false},
+		{"upper", "TRACE", LevelTrace, false},
+		{"warning", "Warning", LevelWarn, false},
+		{"space", " fatal ", LevelFatal, false},
+		{"unknown", "verbose", LevelInfo, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.s)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("ParseLevel(): name %v , got %v %v, want %v", tt.name, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelText(t *testing.T) {
+	var cfg struct {
+		Level Level `json:"level"`
+	}
+	if err := json.Unmarshal([]byte(`{"level":"error"}`), &cfg); err != nil || cfg.Level != LevelError {
+		t.Errorf("UnmarshalText(): got %v %v, want %v", cfg.Level, err, LevelError)
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil || string(data) != `{"level":"error"}` {
+		t.Errorf("MarshalText(): got %s %v", data, err)
+	}
+	if err := json.Unmarshal([]byte(`{"level":"loud"}`), &cfg); err == nil {
+		t.Errorf("UnmarshalText(): want error")
+	}
+}
@@ -0,0 +1,108 @@
+package ulog
+
+import "os"
+
+// sink is what a logger that wraps another logger or handler provides to leveled.
+type sink interface {
+	enabled(level Level) bool
+	output(level Level, msg string, fields []Field)
+	// exit syncs and exits after a fatal record was written.
+	exit()
+}
+
+// loggerEnabled reports whether l logs records at level. Loggers from other
+// packages are assumed to log all levels.
+func loggerEnabled(l Logger, level Level) bool {
+	if e, ok := l.(interface{ enabled(Level) bool }); ok {
+		return e.enabled(level)
+	}
+	return true
+}
+
+// exitLogger syncs l and calls its exit function. Loggers from other
+// packages are synced and the process exits with os.Exit.
+func exitLogger(l Logger) {
+	if e, ok := l.(interface{ exit() }); ok {
+		e.exit()
+		return
+	}
+	_ = l.Sync()
+	os.Exit(1)
+}
+
+// fieldsKeyvals returns msg under MessageKey followed by fields, to pass
+// a record on to Logger.Log.
+func fieldsKeyvals(msg string, fields []Field) []any {
+	keyvals := make([]any, 0, len(fields)+2)
+	keyvals = append(keyvals, MessageKey, msg)
+	for _, f := range fields {
+		keyvals = append(keyvals, f)
+	}
+	return keyvals
+}
+
+// leveled implements Log and the leveled Logger methods on top of a sink.
+type leveled struct {
+	s sink
+}
+
+func (l leveled) Log(level Level, keyvals ...any) {
+	if !l.s.enabled(level) {
+		return
+	}
+	msg, fields := splitMessage(Fields(keyvals...))
+	l.s.output(level, msg, fields)
+}
+
+func (l leveled) print(level Level, v []any) {
+	if l.s.enabled(level) {
+		l.s.output(level, sprint(v...), nil)
+	}
+}
+
+func (l leveled) printf(level Level, format string, v []any) {
+	if l.s.enabled(level) {
+		l.s.output(level, sprintf(format, v...), nil)
+	}
+}
+
+func (l leveled) Trace(v ...any)                 { l.print(LevelTrace, v) }
+func (l leveled) Tracef(format string, v ...any) { l.printf(LevelTrace, format, v) }
+func (l leveled) Debug(v ...any)                 { l.print(LevelDebug, v) }
+func (l leveled) Debugf(format string, v ...any) { l.printf(LevelDebug, format, v) }
+func (l leveled) Info(v ...any)                  { l.print(LevelInfo, v) }
+func (l leveled) Infof(format string, v ...any)  { l.printf(LevelInfo, format, v) }
+func (l leveled) Warn(v ...any)                  { l.print(LevelWarn, v) }
+func (l leveled) Warnf(format string, v ...any)  { l.printf(LevelWarn, format, v) }
+func (l leveled) Error(v ...any)                 { l.print(LevelError, v) }
+func (l leveled) Errorf(format string, v ...any) { l.printf(LevelError, format, v) }
+
+func (l leveled) Panic(v ...any) {
+	l.panic(sprint(v...))
+}
+
+func (l leveled) Panicf(format string, v ...any) {
+	l.panic(sprintf(format, v...))
+}
+
+func (l leveled) panic(msg string) {
+	if l.s.enabled(LevelPanic) {
+		l.s.output(LevelPanic, msg, nil)
+	}
+	panic(msg)
+}
+
+func (l leveled) Fatal(v ...any) {
+	l.fatal(sprint(v...))
+}
+
+func (l leveled) Fatalf(format string, v ...any) {
+	l.fatal(sprintf(format, v...))
+}
+
+func (l leveled) fatal(msg string) {
+	if l.s.enabled(LevelFatal) {
+		l.s.output(LevelFatal, msg, nil)
+	}
+	l.s.exit()
+}
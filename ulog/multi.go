@@ -0,0 +1,83 @@
+package ulog
+
+// multiLogger writes every record to several loggers.
+type multiLogger struct {
+	leveled
+	loggers []Logger
+	level   *levelVar
+}
+
+// NewMultiLogger returns a Logger that writes every record to all loggers.
+// Each logger keeps its own level and encoder, so one call can write colored
+// text at debug to stderr and JSON at info to a file:
+// e.g. NewMultiLogger(NewStdLogger(os.Stderr), NewStdLogger(file, WithLevel(LevelInfo), WithEncoder(NewJSONEncoder(EncoderConfig{}))))
+// SetLevel sets a level that applies before the levels of the loggers, LevelTrace by default.
+func NewMultiLogger(loggers ...Logger) Logger {
+	level := new(levelVar)
+	level.Store(LevelTrace)
+	return newMultiLogger(loggers, level)
+}
+
+func newMultiLogger(loggers []Logger, level *levelVar) *multiLogger {
+	m := &multiLogger{loggers: loggers, level: level}
+	m.leveled = leveled{m}
+	return m
+}
+
+func (m *multiLogger) SetLevel(level Level) {
+	m.level.Store(level)
+}
+
+func (m *multiLogger) getLevel() Level {
+	return m.level.Load()
+}
+
+func (m *multiLogger) With(keyvals ...any) Logger {
+	loggers := make([]Logger, len(m.loggers))
+	for i, l := range m.loggers {
+		loggers[i] = l.With(keyvals...)
+	}
+	return newMultiLogger(loggers, m.level)
+}
+
+// Sync syncs all loggers and returns the first error.
+func (m *multiLogger) Sync() error {
+	var err error
+	for _, l := range m.loggers {
+		if serr := l.Sync(); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+func (m *multiLogger) enabled(level Level) bool {
+	if level < m.level.Load() {
+		return false
+	}
+	for _, l := range m.loggers {
+		if loggerEnabled(l, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *multiLogger) output(level Level, msg string, fields []Field) {
+	keyvals := fieldsKeyvals(msg, fields)
+	for _, l := range m.loggers {
+		if loggerEnabled(l, level) {
+			l.Log(level, keyvals...)
+		}
+	}
+}
+
+// exit syncs all loggers and exits with the exit function of the first one.
+func (m *multiLogger) exit() {
+	_ = m.Sync()
+	if len(m.loggers) == 0 {
+		exitLogger(StdLogger)
+		return
+	}
+	exitLogger(m.loggers[0])
+}
@@ -0,0 +1,58 @@
+package ulog
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMultiLogger(t *testing.T) {
+	var text, jsonBuf, errBuf bytes.Buffer
+	l := NewMultiLogger(
+		NewStdLogger(&text, WithColor(ColorAlways), WithCaller(true)),
+		NewStdLogger(&jsonBuf, WithLevel(LevelInfo), WithEncoder(NewJSONEncoder(EncoderConfig{TimeKey: OmitKey}))),
+		NewStdLogger(&errBuf, WithLevel(LevelError), WithEncoder(NewTextEncoder(EncoderConfig{TimeKey: OmitKey}))),
+	).With("a", 1)
+
+	want := line() + 1
+	l.Debug("debug")
+	l.Info("info")
+	l.Log(LevelError, MessageKey, "error", "b", 2)
+
+	if got := strings.Count(text.String(), "\n"); got != 3 {
+		t.Errorf("text sink: got %v records, want 3", got)
+	}
+	if !strings.HasPrefix(text.String(), DebugPrefix) || !strings.Contains(text.String(), fmt.Sprintf("ulog/multi_test.go:%d debug a=1", want)) {
+		t.Errorf("text sink: got %q", text.String())
+	}
+	wantJSON := `{"level":"INFO","msg":"info","a":1}` + "\n" + `{"level":"ERROR","msg":"error","a":1,"b":2}` + "\n"
+	if jsonBuf.String() != wantJSON {
+		t.Errorf("json sink: got %v, want %v", jsonBuf.String(), wantJSON)
+	}
+	if want := "level=ERROR msg=error a=1 b=2\n"; errBuf.String() != want {
+		t.Errorf("error sink: got %v, want %v", errBuf.String(), want)
+	}
+
+	l.SetLevel(LevelError)
+	l.Warn("hidden")
+	if strings.Contains(text.String(), "hidden") {
+		t.Errorf("SetLevel(): got %q", text.String())
+	}
+}
+
+func TestMultiLoggerFatal(t *testing.T) {
+	var a, b syncBuffer
+	exits := 0
+	l := NewMultiLogger(
+		NewStdLogger(&a, WithExitFunc(func(int) { exits++ })),
+		NewStdLogger(&b, WithExitFunc(func(int) { exits += 10 })),
+	)
+	l.Fatal("fatal")
+	if exits != 1 || !a.synced || !b.synced {
+		t.Errorf("Fatal(): got exits %v synced %v %v, want 1 true true", exits, a.synced, b.synced)
+	}
+	if !strings.Contains(a.String(), "fatal") || !strings.Contains(b.String(), "fatal") {
+		t.Errorf("Fatal(): got %q and %q", a.String(), b.String())
+	}
+}
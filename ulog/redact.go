@@ -0,0 +1,143 @@
+package ulog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RedactedMask is the default replacement of redacted values.
+const RedactedMask = "***"
+
+// Redacter is implemented by values that hide sensitive data in logs.
+// Redacted returns the value logged in their place, both in fields and in
+// messages, whether or not the logger uses WithRedaction.
+type Redacter interface {
+	Redacted() any
+}
+
+// RedactConfig configures WithRedaction.
+type RedactConfig struct {
+	// Keys are the field keys whose values are masked, case-insensitive.
+	// e.g. []string{"password", "authorization"}
+	Keys []string
+	// Patterns mask their matches in messages and in the text of field values.
+	// e.g. regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`) for card numbers
+	Patterns []*regexp.Regexp
+	// Mask replaces masked values and matches, RedactedMask by default.
+	Mask string
+}
+
+// WithRedaction masks sensitive data in records before they are encoded.
+func WithRedaction(cfg RedactConfig) Option {
+	return func(o *options) {
+		o.redact = cfg
+	}
+}
+
+// redactor masks the sensitive data of records.
+type redactor struct {
+	keys     map[string]struct{}
+	patterns []*regexp.Regexp
+	mask     string
+}
+
+func newRedactor(cfg RedactConfig) *redactor {
+	rd := &redactor{
+		keys:     make(map[string]struct{}, len(cfg.Keys)),
+		patterns: cfg.Patterns,
+		mask:     cfg.Mask,
+	}
+	for _, key := range cfg.Keys {
+		rd.keys[strings.ToLower(key)] = struct{}{}
+	}
+	if rd.mask == "" {
+		rd.mask = RedactedMask
+	}
+	return rd
+}
+
+// record masks the message and fields of r. Fields shared with the logger
+// are copied before they are changed.
+func (rd *redactor) record(r *Record) {
+	r.Message = rd.text(r.Message)
+	r.Fields, _ = rd.fields(r.Fields)
+}
+
+// fields returns fields with their values masked and whether any was.
+func (rd *redactor) fields(fields []Field) ([]Field, bool) {
+	var out []Field
+	for i, f := range fields {
+		v, changed := rd.value(f.Key, f.Value)
+		if !changed {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		out = append(out, Field{Key: f.Key, Value: v})
+	}
+	if out == nil {
+		return fields, false
+	}
+	return out, true
+}
+
+func (rd *redactor) value(key string, v any) (any, bool) {
+	if _, ok := rd.keys[strings.ToLower(key)]; ok {
+		return rd.mask, true
+	}
+	switch v := v.(type) {
+	case Redacter:
+		return redacted(v), true
+	case []Field:
+		return rd.fields(v)
+	case string:
+		s := rd.text(v)
+		return s, s != v
+	case error, fmt.Stringer:
+		if len(rd.patterns) == 0 {
+			return v, false
+		}
+		s := valueString(v)
+		if masked := rd.text(s); masked != s {
+			return masked, true
+		}
+	}
+	return v, false
+}
+
+// text replaces the matches of the patterns in s with the mask.
+func (rd *redactor) text(s string) string {
+	for _, p := range rd.patterns {
+		s = p.ReplaceAllLiteralString(s, rd.mask)
+	}
+	return s
+}
+
+// redactArgs returns v with Redacter values replaced, for formatting messages.
+func redactArgs(v []any) []any {
+	var out []any
+	for i, a := range v {
+		r, ok := a.(Redacter)
+		if !ok {
+			if out != nil {
+				out = append(out, a)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]any, i, len(v))
+			copy(out, v[:i])
+		}
+		out = append(out, redacted(r))
+	}
+	if out == nil {
+		return v
+	}
+	return out
+}
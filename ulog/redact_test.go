@@ -0,0 +1,62 @@
+package ulog
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+type secret string
+
+func (s secret) Redacted() any { return "secret(" + string(s[:1]) + "...)" }
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	card := regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	base := NewStdLogger(&buf,
+		WithEncoder(NewTextEncoder(EncoderConfig{TimeKey: OmitKey, LevelKey: OmitKey})),
+		WithRedaction(RedactConfig{Keys: []string{"password", "Authorization"}, Patterns: []*regexp.Regexp{card}}),
+	)
+	l := base.With("user", map[string]any{"name": "bob", "password": "hunter2"})
+
+	tests := []struct {
+		name string
+		log  func()
+		want string
+	}{
+		{"key", func() { l.Log(LevelInfo, MessageKey, "login", "PASSWORD", "hunter2", "authorization", "Bearer x") }, `msg=login user.name=bob user.password=*** PASSWORD=*** authorization=***`},
+		{"pattern", func() {
+			base.Info("paid with 4111 1111 1111 1111", "card", "4111-1111-1111-1111", "err", errors.New("bad card 4111111111111111"))
+		}, `msg="paid with *** card *** err bad card ***"`},
+		{"pattern field", func() {
+			base.Log(LevelInfo, MessageKey, "paid", "card", "4111111111111111", "err", errors.New("bad card 4111111111111111"), "n", 4111)
+		}, `msg=paid card=*** err="bad card ***" n=4111`},
+		{"printf", func() { base.Infof("token %v card %s", secret("abc"), "4111111111111111") }, `msg="token secret(a...) card ***"`},
+		{"redacter field", func() { base.With("token", secret("xyz")).Warn("w") }, `msg=w token=secret(x...)`},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		tt.log()
+		if got := buf.String(); got != tt.want+"\n" {
+			t.Errorf("WithRedaction(): name %v , got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// The fields of the logger are not changed by redaction.
+	buf.Reset()
+	NewStdLogger(&buf, WithEncoder(NewTextEncoder(EncoderConfig{TimeKey: OmitKey, LevelKey: OmitKey}))).Info(l.(*stdLogger).fields)
+	if want := "msg=\"[{user [{name bob} {password hunter2}]}]\"\n"; buf.String() != want {
+		t.Errorf("WithRedaction(): got %v, want %v", buf.String(), want)
+	}
+}
+
+func TestRedacterWithoutRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(&buf, WithEncoder(NewJSONEncoder(EncoderConfig{TimeKey: OmitKey})))
+	l.Log(LevelInfo, MessageKey, secret("msg"), "token", secret("abc"), "password", "visible")
+	want := `{"level":"INFO","msg":"secret(m...)","token":"secret(a...)","password":"visible"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("Redacter: got %v, want %v", buf.String(), want)
+	}
+}
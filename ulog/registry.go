@@ -0,0 +1,207 @@
+package ulog
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// LoggerKey is the key of the name of a named logger.
+const LoggerKey = "logger"
+
+// registry holds the named loggers and their level overrides.
+var registry = struct {
+	mu        sync.RWMutex
+	loggers   map[string]*namedEntry
+	overrides map[string]Level
+}{
+	loggers:   map[string]*namedEntry{},
+	overrides: map[string]Level{},
+}
+
+// Default returns the default logger, StdLogger unless changed by SetDefault.
+func Default() Logger {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	return StdLogger
+}
+
+// SetDefault sets StdLogger to l. Named loggers write to l from now on,
+// including those created before.
+func SetDefault(l Logger) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	StdLogger = l
+	for _, e := range registry.loggers {
+		e.setParent(l)
+	}
+}
+
+// Named returns the logger registered under name, creating it if needed.
+// It writes to the default logger with name added under LoggerKey, and its
+// level can be changed with SetLevelFor. Dotted names form a hierarchy, so
+// the level of "db" also applies to "db.pool" unless it has its own.
+func Named(name string) Logger {
+	registry.mu.RLock()
+	e, ok := registry.loggers[name]
+	registry.mu.RUnlock()
+	if !ok {
+		registry.mu.Lock()
+		if e, ok = registry.loggers[name]; !ok {
+			e = &namedEntry{name: name}
+			e.setParent(StdLogger)
+			registry.loggers[name] = e
+		}
+		registry.mu.Unlock()
+	}
+	return newNamedLogger(e, nil)
+}
+
+// Names returns the names of the registered named loggers, sorted.
+func Names() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	names := make([]string, 0, len(registry.loggers))
+	for name := range registry.loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetLevelFor sets the level of the named logger name and the loggers below
+// it, overriding the level of the default logger. The loggers of a multi
+// logger still filter records by their own levels.
+// e.g. SetLevelFor("db", LevelDebug)
+func SetLevelFor(name string, level Level) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.overrides[name] = level
+}
+
+// ResetLevelFor removes the level set by SetLevelFor for name.
+func ResetLevelFor(name string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	delete(registry.overrides, name)
+}
+
+// LevelFor returns the level that applies to the named logger name and
+// whether it is overridden. Without an override it is the level of the
+// default logger.
+func LevelFor(name string) (Level, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	if level, ok := overrideFor(name); ok {
+		return level, true
+	}
+	return loggerLevel(StdLogger), false
+}
+
+// overrideFor returns the override of name or its closest parent name.
+// It must be called with registry.mu held.
+func overrideFor(name string) (Level, bool) {
+	for {
+		if level, ok := registry.overrides[name]; ok {
+			return level, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+// loggerLevel returns the level of l, LevelTrace for loggers from other packages.
+func loggerLevel(l Logger) Level {
+	if g, ok := l.(interface{ getLevel() Level }); ok {
+		return g.getLevel()
+	}
+	return LevelTrace
+}
+
+// forceLog writes a record to l regardless of the level of l, for named
+// loggers with a level override. Loggers from other packages filter it by
+// their level.
+func forceLog(l Logger, level Level, msg string, fields []Field) {
+	if s, ok := l.(sink); ok {
+		s.output(level, msg, fields)
+		return
+	}
+	l.Log(level, fieldsKeyvals(msg, fields)...)
+}
+
+// namedEntry is a registered named logger.
+type namedEntry struct {
+	name   string
+	parent atomic.Value // holds a parentLogger
+}
+
+type parentLogger struct {
+	Logger
+}
+
+func (e *namedEntry) setParent(l Logger) {
+	e.parent.Store(parentLogger{l.With(LoggerKey, e.name)})
+}
+
+func (e *namedEntry) getParent() Logger {
+	return e.parent.Load().(parentLogger).Logger
+}
+
+// namedLogger writes to the parent of its entry with its fields added.
+type namedLogger struct {
+	leveled
+	entry  *namedEntry
+	fields []Field
+}
+
+func newNamedLogger(e *namedEntry, fields []Field) *namedLogger {
+	n := &namedLogger{entry: e, fields: fields}
+	n.leveled = leveled{n}
+	return n
+}
+
+// SetLevel sets the level of the named logger, like SetLevelFor.
+func (n *namedLogger) SetLevel(level Level) {
+	SetLevelFor(n.entry.name, level)
+}
+
+func (n *namedLogger) With(keyvals ...any) Logger {
+	fields := make([]Field, 0, len(n.fields)+len(keyvals)/2)
+	fields = append(fields, n.fields...)
+	fields = append(fields, Fields(keyvals...)...)
+	return newNamedLogger(n.entry, fields)
+}
+
+func (n *namedLogger) Sync() error {
+	return n.entry.getParent().Sync()
+}
+
+func (n *namedLogger) getLevel() Level {
+	level, _ := LevelFor(n.entry.name)
+	return level
+}
+
+func (n *namedLogger) enabled(level Level) bool {
+	registry.mu.RLock()
+	override, ok := overrideFor(n.entry.name)
+	registry.mu.RUnlock()
+	if ok {
+		return level >= override
+	}
+	return loggerEnabled(n.entry.getParent(), level)
+}
+
+func (n *namedLogger) output(level Level, msg string, fields []Field) {
+	all := make([]Field, 0, len(n.fields)+len(fields))
+	all = append(all, n.fields...)
+	all = append(all, fields...)
+	forceLog(n.entry.getParent(), level, msg, all)
+}
+
+func (n *namedLogger) exit() {
+	exitLogger(n.entry.getParent())
+}
@@ -0,0 +1,103 @@
+package ulog
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// resetRegistry sets the default logger to l and empties the registry until the test ends.
+func resetRegistry(t *testing.T, l Logger) {
+	registry.mu.Lock()
+	saved, loggers, overrides := StdLogger, registry.loggers, registry.overrides
+	StdLogger = l
+	registry.loggers = map[string]*namedEntry{}
+	registry.overrides = map[string]Level{}
+	registry.mu.Unlock()
+	t.Cleanup(func() {
+		registry.mu.Lock()
+		StdLogger, registry.loggers, registry.overrides = saved, loggers, overrides
+		registry.mu.Unlock()
+	})
+}
+
+func TestNamed(t *testing.T) {
+	var buf bytes.Buffer
+	resetRegistry(t, NewStdLogger(&buf, WithLevel(LevelInfo), WithEncoder(NewTextEncoder(EncoderConfig{TimeKey: OmitKey}))))
+
+	db := Named("db").With("a", 1)
+	db.Debug("hidden")
+	db.Info("info")
+	SetLevelFor("db", LevelDebug)
+	db.Debug("debug")
+	Named("db.pool").Log(LevelDebug, MessageKey, "pool", "b", 2)
+	Named("http").Debug("hidden")
+	SetLevelFor("db.pool", LevelError)
+	Named("db.pool").Warn("hidden")
+	ResetLevelFor("db")
+	db.Debug("hidden")
+
+	want := "level=INFO msg=info logger=db a=1\n" +
+		"level=DEBUG msg=debug logger=db a=1\n" +
+		"level=DEBUG msg=pool logger=db.pool b=2\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Named(): got %v, want %v", got, want)
+	}
+	if got, want := Names(), []string{"db", "db.pool", "http"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names(): got %v, want %v", got, want)
+	}
+}
+
+func TestLevelFor(t *testing.T) {
+	resetRegistry(t, NewStdLogger(&bytes.Buffer{}, WithLevel(LevelWarn)))
+	SetLevelFor("db", LevelDebug)
+	Named("api").SetLevel(LevelError)
+
+	tests := []struct {
+		name     string
+		want     Level
+		override bool
+	}{
+		{"db", LevelDebug, true},
+		{"db.pool", LevelDebug, true},
+		{"dbx", LevelWarn, false},
+		{"api", LevelError, true},
+		{"http", LevelWarn, false},
+	}
+	for _, tt := range tests {
+		if got, override := LevelFor(tt.name); got != tt.want || override != tt.override {
+			t.Errorf("LevelFor(): name %v , got %v %v, want %v %v", tt.name, got, override, tt.want, tt.override)
+		}
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	var before, after bytes.Buffer
+	resetRegistry(t, NewStdLogger(&before))
+	l := Named("db")
+	SetDefault(NewStdLogger(&after, WithEncoder(NewJSONEncoder(EncoderConfig{TimeKey: OmitKey}))))
+	l.Info("info")
+
+	if before.Len() != 0 {
+		t.Errorf("SetDefault(): got %q written to the previous logger", before.String())
+	}
+	if want := `{"level":"INFO","msg":"info","logger":"db"}` + "\n"; after.String() != want {
+		t.Errorf("SetDefault(): got %v, want %v", after.String(), want)
+	}
+	if Default() != StdLogger {
+		t.Errorf("Default(): got %v, want StdLogger", Default())
+	}
+}
+
+func TestNamedMulti(t *testing.T) {
+	var all, errs bytes.Buffer
+	m := NewMultiLogger(NewStdLogger(&all), NewStdLogger(&errs, WithLevel(LevelError)))
+	m.SetLevel(LevelInfo)
+	resetRegistry(t, m)
+	SetLevelFor("db", LevelTrace)
+	Named("db").Debug("debug")
+	if !strings.Contains(all.String(), "debug logger=db") || errs.Len() != 0 {
+		t.Errorf("Named(): got %q and %q", all.String(), errs.String())
+	}
+}
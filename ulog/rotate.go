@@ -0,0 +1,386 @@
+package ulog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xbmlz/guc/ufile"
+)
+
+// RotateInterval is the period after which a RotateWriter starts a new file.
+type RotateInterval int
+
+const (
+	// RotateNone disables time based rotation.
+	RotateNone RotateInterval = iota
+	// RotateHourly rotates the file at the start of every hour.
+	RotateHourly
+	// RotateDaily rotates the file at midnight.
+	RotateDaily
+)
+
+// backupTimeFormat is the timestamp in the names of rotated files.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+const compressSuffix = ".gz"
+
+// RotateConfig configures a RotateWriter.
+type RotateConfig struct {
+	// Filename is the file to write to. Rotated files are kept in the same
+	// directory as name-2006-01-02T15-04-05.000.ext.
+	Filename string
+	// MaxSize is the size in bytes at which the file is rotated, 0 disables size rotation.
+	MaxSize int64
+	// Interval rotates the file hourly or daily.
+	Interval RotateInterval
+	// MaxAge removes rotated files older than this, 0 keeps them regardless of age.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files to keep, 0 keeps all of them.
+	MaxBackups int
+	// Compress gzips rotated files.
+	Compress bool
+	// UTC uses UTC instead of local time for interval boundaries and file names.
+	UTC bool
+}
+
+// RotateWriter is an io.Writer that writes to a file and rotates it by size,
+// by time or both. It is safe for concurrent use, so one RotateWriter can be
+// shared by several loggers.
+// e.g. NewStdLogger(w, WithEncoder(NewJSONEncoder(EncoderConfig{})))
+type RotateWriter struct {
+	cfg RotateConfig
+	now func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	period time.Time
+
+	millOnce sync.Once
+	millCh   chan struct{}
+	millWg   sync.WaitGroup
+}
+
+// NewRotateWriter opens or creates cfg.Filename and returns a RotateWriter for it.
+func NewRotateWriter(cfg RotateConfig) (*RotateWriter, error) {
+	if cfg.Filename == "" {
+		return nil, fmt.Errorf("ulog: rotate: empty filename")
+	}
+	if cfg.MaxSize < 0 || cfg.MaxAge < 0 || cfg.MaxBackups < 0 {
+		return nil, fmt.Errorf("ulog: rotate: negative limit")
+	}
+	w := &RotateWriter{
+		cfg: cfg,
+		now: time.Now,
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.openExisting(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write writes p to the current file, rotating it first if p does not fit
+// in MaxSize or the rotation interval has passed.
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := int64(len(p))
+	if w.cfg.MaxSize > 0 && n > w.cfg.MaxSize {
+		return 0, fmt.Errorf("ulog: rotate: write of %s exceeds max size %s", ufile.FormatSize(n), ufile.FormatSize(w.cfg.MaxSize))
+	}
+	if w.file == nil {
+		if err := w.openExisting(); err != nil {
+			return 0, err
+		}
+	}
+	if w.size > 0 && (w.cfg.MaxSize > 0 && w.size+n > w.cfg.MaxSize || w.expired()) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	written, err := w.file.Write(p)
+	w.size += int64(written)
+	return written, err
+}
+
+// Rotate closes the current file, renames it with a timestamp and opens a new one.
+func (w *RotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate()
+}
+
+// Sync commits the current file to stable storage.
+func (w *RotateWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close closes the current file and waits for pending compression and cleanup.
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	err := w.close()
+	if w.millCh != nil {
+		close(w.millCh)
+		w.millCh = nil
+	}
+	w.mu.Unlock()
+	w.millWg.Wait()
+	return err
+}
+
+func (w *RotateWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *RotateWriter) time() time.Time {
+	if w.cfg.UTC {
+		return w.now().UTC()
+	}
+	return w.now()
+}
+
+// periodStart returns the start of the rotation interval that contains t.
+func (w *RotateWriter) periodStart(t time.Time) time.Time {
+	switch w.cfg.Interval {
+	case RotateHourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case RotateDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	return time.Time{}
+}
+
+// expired reports whether the interval of the current file has passed.
+func (w *RotateWriter) expired() bool {
+	if w.cfg.Interval == RotateNone {
+		return false
+	}
+	return !w.periodStart(w.time()).Equal(w.period)
+}
+
+// openExisting opens the file for appending, creating it if needed.
+func (w *RotateWriter) openExisting() error {
+	if err := ufile.MkdirAll(filepath.Dir(w.cfg.Filename)); err != nil {
+		return fmt.Errorf("ulog: rotate: %w", err)
+	}
+	file, err := os.OpenFile(w.cfg.Filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("ulog: rotate: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("ulog: rotate: %w", err)
+	}
+	modTime := info.ModTime()
+	if w.cfg.UTC {
+		modTime = modTime.UTC()
+	}
+	w.file = file
+	w.size = info.Size()
+	w.period = w.periodStart(w.time())
+	if w.size > 0 {
+		w.period = w.periodStart(modTime)
+	}
+	return nil
+}
+
+func (w *RotateWriter) rotate() error {
+	if err := w.close(); err != nil {
+		return fmt.Errorf("ulog: rotate: %w", err)
+	}
+	if ufile.IsExist(w.cfg.Filename) {
+		if err := os.Rename(w.cfg.Filename, w.backupName(w.time())); err != nil {
+			return fmt.Errorf("ulog: rotate: %w", err)
+		}
+	}
+	file, err := os.OpenFile(w.cfg.Filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("ulog: rotate: %w", err)
+	}
+	w.file = file
+	w.size = 0
+	w.period = w.periodStart(w.time())
+	w.mill()
+	return nil
+}
+
+// backupName returns a name for a rotated file that does not exist yet.
+func (w *RotateWriter) backupName(t time.Time) string {
+	dir, prefix, ext := w.nameParts()
+	name := filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+	for i := 1; ufile.IsExist(name) || ufile.IsExist(name+compressSuffix); i++ {
+		name = filepath.Join(dir, fmt.Sprintf("%s%s.%d%s", prefix, t.Format(backupTimeFormat), i, ext))
+	}
+	return name
+}
+
+func (w *RotateWriter) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(w.cfg.Filename)
+	base := filepath.Base(w.cfg.Filename)
+	ext = filepath.Ext(base)
+	prefix = strings.TrimSuffix(base, ext) + "-"
+	return dir, prefix, ext
+}
+
+// mill starts the background cleanup and compression of rotated files.
+// It must be called with w.mu held.
+func (w *RotateWriter) mill() {
+	if w.cfg.MaxAge == 0 && w.cfg.MaxBackups == 0 && !w.cfg.Compress {
+		return
+	}
+	w.millOnce.Do(func() {
+		w.millCh = make(chan struct{}, 1)
+		w.millWg.Add(1)
+		go func(ch chan struct{}) {
+			defer w.millWg.Done()
+			for range ch {
+				if err := w.millRun(); err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+				}
+			}
+		}(w.millCh)
+	})
+	if w.millCh == nil {
+		return
+	}
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+}
+
+// backups returns the rotated files, newest first.
+func (w *RotateWriter) backups() ([]backupFile, error) {
+	dir, prefix, ext := w.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.Local
+	if w.cfg.UTC {
+		loc = time.UTC
+	}
+	var files []backupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), compressSuffix)
+		if !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		stamp = strings.TrimSuffix(stamp, ext)
+		if len(stamp) < len(backupTimeFormat) {
+			continue
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, stamp[:len(backupTimeFormat)], loc)
+		if err != nil {
+			continue
+		}
+		files = append(files, backupFile{path: filepath.Join(dir, name), time: t})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].time.Equal(files[j].time) {
+			return files[i].path > files[j].path
+		}
+		return files[i].time.After(files[j].time)
+	})
+	return files, nil
+}
+
+// millRun removes rotated files beyond MaxBackups or MaxAge and compresses the rest.
+func (w *RotateWriter) millRun() error {
+	files, err := w.backups()
+	if err != nil {
+		return fmt.Errorf("ulog: rotate: %w", err)
+	}
+	var cutoff time.Time
+	if w.cfg.MaxAge > 0 {
+		cutoff = w.time().Add(-w.cfg.MaxAge)
+	}
+	var errs []string
+	for i, f := range files {
+		if (w.cfg.MaxBackups > 0 && i >= w.cfg.MaxBackups) || (!cutoff.IsZero() && f.time.Before(cutoff)) {
+			if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err.Error())
+			}
+			continue
+		}
+		if w.cfg.Compress && !strings.HasSuffix(f.path, compressSuffix) {
+			if err := compressFile(f.path); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("ulog: rotate: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// compressFile gzips src into src.gz and removes src.
+func compressFile(src string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst := src + compressSuffix
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(tmp)
+		}
+	}()
+	gz := gzip.NewWriter(out)
+	if _, err = io.Copy(gz, in); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,153 @@
+package ulog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func readBackups(t *testing.T, w *RotateWriter) []backupFile {
+	t.Helper()
+	files, err := w.backups()
+	if err != nil {
+		t.Fatalf("backups(): %v", err)
+	}
+	return files
+}
+
+func TestRotateWriterSize(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "logs", "app.log")
+	w, err := NewRotateWriter(RotateConfig{Filename: filename, MaxSize: 10})
+	if err != nil {
+		t.Fatalf("NewRotateWriter(): %v", err)
+	}
+	defer w.Close()
+	for _, s := range []string{"12345\n", "12345\n", "12345\n"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatalf("Write(): %v", err)
+		}
+	}
+	if got := len(readBackups(t, w)); got != 2 {
+		t.Errorf("Write(): got %v backups, want 2", got)
+	}
+	if _, err := w.Write(bytes.Repeat([]byte("x"), 11)); err == nil || !strings.Contains(err.Error(), "11.00 B") {
+		t.Errorf("Write(): got %v, want max size error", err)
+	}
+}
+
+func TestRotateWriterInterval(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotateWriter(RotateConfig{Filename: filename, Interval: RotateHourly, UTC: true})
+	if err != nil {
+		t.Fatalf("NewRotateWriter(): %v", err)
+	}
+	defer w.Close()
+	now := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+	w.period = w.periodStart(now)
+
+	w.Write([]byte("a\n"))
+	now = now.Add(40 * time.Minute)
+	w.Write([]byte("b\n"))
+	files := readBackups(t, w)
+	if len(files) != 1 {
+		t.Fatalf("Write(): got %v backups, want 1", len(files))
+	}
+	if want := filepath.Join(filepath.Dir(filename), "app-2024-01-01T11-10-00.000.log"); files[0].path != want {
+		t.Errorf("Write(): got %v, want %v", files[0].path, want)
+	}
+	if got, _ := os.ReadFile(filename); string(got) != "b\n" {
+		t.Errorf("Write(): got %q, want %q", got, "b\n")
+	}
+}
+
+func TestRotateWriterCleanup(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotateWriter(RotateConfig{Filename: filename, MaxBackups: 2, Compress: true, UTC: true})
+	if err != nil {
+		t.Fatalf("NewRotateWriter(): %v", err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+	for i := 0; i < 4; i++ {
+		w.Write([]byte("line\n"))
+		now = now.Add(time.Minute)
+		if err := w.Rotate(); err != nil {
+			t.Fatalf("Rotate(): %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+	files := readBackups(t, w)
+	if len(files) != 2 {
+		t.Fatalf("Rotate(): got %v backups, want 2", len(files))
+	}
+	for _, f := range files {
+		if !strings.HasSuffix(f.path, compressSuffix) {
+			t.Errorf("Rotate(): %v is not compressed", f.path)
+			continue
+		}
+		file, _ := os.Open(f.path)
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("gzip.NewReader(): %v", err)
+		}
+		data, _ := io.ReadAll(gz)
+		file.Close()
+		if string(data) != "line\n" {
+			t.Errorf("Rotate(): got %q, want %q", data, "line\n")
+		}
+	}
+}
+
+func TestRotateWriterShared(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotateWriter(RotateConfig{Filename: filename, MaxSize: 1024})
+	if err != nil {
+		t.Fatalf("NewRotateWriter(): %v", err)
+	}
+	loggers := []Logger{
+		NewStdLogger(w),
+		NewStdLogger(w, WithEncoder(NewJSONEncoder(EncoderConfig{}))),
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(l Logger) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				l.Info("shared writer")
+			}
+		}(loggers[i%2])
+	}
+	wg.Wait()
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+
+	lines := 0
+	paths := []string{filename}
+	for _, f := range readBackups(t, w) {
+		paths = append(paths, f.path)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile(): %v", err)
+		}
+		if len(data) > 1024 {
+			t.Errorf("Write(): %v has %v bytes, want <= 1024", path, len(data))
+		}
+		lines += strings.Count(string(data), "\n")
+	}
+	if lines != 20*50 {
+		t.Errorf("Write(): got %v lines, want %v", lines, 20*50)
+	}
+}
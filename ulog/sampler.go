@@ -0,0 +1,294 @@
+package ulog
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// numLevels is the number of levels from LevelTrace to LevelFatal.
+const numLevels = int(LevelFatal-LevelTrace) + 1
+
+// levelIndex returns the index of level in per-level tables, clamped to the known levels.
+func levelIndex(level Level) int {
+	switch {
+	case level < LevelTrace:
+		return 0
+	case level > LevelFatal:
+		return numLevels - 1
+	}
+	return int(level - LevelTrace)
+}
+
+// dropCounter counts dropped records per level. It is safe for concurrent use.
+type dropCounter struct {
+	counts [numLevels]uint64
+}
+
+func (c *dropCounter) drop(level Level) {
+	atomic.AddUint64(&c.counts[levelIndex(level)], 1)
+}
+
+// Dropped returns the number of records dropped at level.
+func (c *dropCounter) Dropped(level Level) uint64 {
+	return atomic.LoadUint64(&c.counts[levelIndex(level)])
+}
+
+// TotalDropped returns the number of records dropped at all levels.
+func (c *dropCounter) TotalDropped() uint64 {
+	var total uint64
+	for i := range c.counts {
+		total += atomic.LoadUint64(&c.counts[i])
+	}
+	return total
+}
+
+// SamplerConfig configures NewSampler.
+type SamplerConfig struct {
+	// Interval is the period in which records are counted, 1s by default.
+	Interval time.Duration
+	// First is the number of records with the same level and message logged in each interval.
+	First int
+	// Thereafter logs every Thereafter-th record after First in each interval, 0 drops them.
+	Thereafter int
+}
+
+// samplerTableSize is the number of message counters per level. Messages
+// are hashed into the table, so memory stays bounded.
+const samplerTableSize = 4096
+
+type samplerCounter struct {
+	resetAt int64
+	count   uint64
+}
+
+// inc counts a record at now and returns its number in the current interval.
+func (c *samplerCounter) inc(now int64, interval time.Duration) uint64 {
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if resetAt > now {
+		return atomic.AddUint64(&c.count, 1)
+	}
+	atomic.StoreUint64(&c.count, 1)
+	if !atomic.CompareAndSwapInt64(&c.resetAt, resetAt, now+int64(interval)) {
+		// Another goroutine started the interval, count this record in it.
+		return atomic.AddUint64(&c.count, 1)
+	}
+	return 1
+}
+
+// samplerState is shared by a Sampler and the children created by With.
+type samplerState struct {
+	// counters and dropCounter hold 64-bit atomics and come first, as only
+	// the first word of an allocated struct is 64-bit aligned on 32-bit
+	// platforms. The size of counters keeps dropCounter aligned.
+	counters [numLevels][samplerTableSize]samplerCounter
+	dropCounter
+	cfg SamplerConfig
+	now func() time.Time
+}
+
+// Sampler is a Logger that logs the first records with the same level and
+// message in each interval and then only every Thereafter-th one, to bound
+// the output of hot paths. Panic and fatal records are never dropped.
+// It is safe for concurrent use.
+type Sampler struct {
+	leveled
+	l     Logger
+	state *samplerState
+}
+
+var _ Logger = (*Sampler)(nil)
+
+// NewSampler returns a Sampler that writes the sampled records to l.
+func NewSampler(l Logger, cfg SamplerConfig) *Sampler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	return newSampler(l, &samplerState{cfg: cfg, now: time.Now})
+}
+
+func newSampler(l Logger, state *samplerState) *Sampler {
+	s := &Sampler{l: l, state: state}
+	s.leveled = leveled{s}
+	return s
+}
+
+// Dropped returns the number of records dropped at level.
+func (s *Sampler) Dropped(level Level) uint64 {
+	return s.state.Dropped(level)
+}
+
+// TotalDropped returns the number of records dropped at all levels.
+func (s *Sampler) TotalDropped() uint64 {
+	return s.state.TotalDropped()
+}
+
+func (s *Sampler) SetLevel(level Level) {
+	s.l.SetLevel(level)
+}
+
+func (s *Sampler) getLevel() Level {
+	return loggerLevel(s.l)
+}
+
+func (s *Sampler) With(keyvals ...any) Logger {
+	return newSampler(s.l.With(keyvals...), s.state)
+}
+
+func (s *Sampler) Sync() error {
+	return s.l.Sync()
+}
+
+func (s *Sampler) enabled(level Level) bool {
+	return loggerEnabled(s.l, level)
+}
+
+func (s *Sampler) output(level Level, msg string, fields []Field) {
+	if level < LevelPanic && !s.sample(level, msg) {
+		s.state.drop(level)
+		return
+	}
+	forceLog(s.l, level, msg, fields)
+}
+
+func (s *Sampler) exit() {
+	exitLogger(s.l)
+}
+
+// sample reports whether a record with level and msg is logged.
+func (s *Sampler) sample(level Level, msg string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(msg))
+	c := &s.state.counters[levelIndex(level)][h.Sum32()%samplerTableSize]
+	n := c.inc(s.state.now().UnixNano(), s.state.cfg.Interval)
+	first := uint64(s.state.cfg.First)
+	if n <= first {
+		return true
+	}
+	thereafter := uint64(s.state.cfg.Thereafter)
+	return thereafter > 0 && (n-first)%thereafter == 0
+}
+
+// RateLimit is the token bucket of a level: Rate records per second on
+// average with bursts of up to Burst records. A zero Burst is the Rate
+// rounded up, and at least 1.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token at now if one is available.
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.last.IsZero() {
+		b.tokens = float64(b.limit.Burst)
+	} else if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.limit.Rate
+		if b.tokens > float64(b.limit.Burst) {
+			b.tokens = float64(b.limit.Burst)
+		}
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// rateLimiterState is shared by a RateLimiter and the children created by With.
+type rateLimiterState struct {
+	dropCounter
+	now     func() time.Time
+	buckets [numLevels]*tokenBucket
+}
+
+// RateLimiter is a Logger that limits the records of each level with a
+// token bucket. Levels without a limit, and panic and fatal records, are
+// never dropped. It is safe for concurrent use.
+type RateLimiter struct {
+	leveled
+	l     Logger
+	state *rateLimiterState
+}
+
+var _ Logger = (*RateLimiter)(nil)
+
+// NewRateLimiter returns a RateLimiter that writes the allowed records to l.
+// It panics if a Rate or Burst is negative.
+// e.g. NewRateLimiter(l, map[Level]RateLimit{LevelDebug: {Rate: 100, Burst: 10}})
+func NewRateLimiter(l Logger, limits map[Level]RateLimit) *RateLimiter {
+	state := &rateLimiterState{now: time.Now}
+	for level, limit := range limits {
+		if limit.Rate < 0 || limit.Burst < 0 {
+			panic(fmt.Sprintf("ulog: negative rate limit %+v for level %v", limit, level))
+		}
+		if limit.Burst == 0 {
+			limit.Burst = int(math.Ceil(limit.Rate))
+			if limit.Burst < 1 {
+				limit.Burst = 1
+			}
+		}
+		state.buckets[levelIndex(level)] = &tokenBucket{limit: limit}
+	}
+	return newRateLimiter(l, state)
+}
+
+func newRateLimiter(l Logger, state *rateLimiterState) *RateLimiter {
+	r := &RateLimiter{l: l, state: state}
+	r.leveled = leveled{r}
+	return r
+}
+
+// Dropped returns the number of records dropped at level.
+func (r *RateLimiter) Dropped(level Level) uint64 {
+	return r.state.Dropped(level)
+}
+
+// TotalDropped returns the number of records dropped at all levels.
+func (r *RateLimiter) TotalDropped() uint64 {
+	return r.state.TotalDropped()
+}
+
+func (r *RateLimiter) SetLevel(level Level) {
+	r.l.SetLevel(level)
+}
+
+func (r *RateLimiter) getLevel() Level {
+	return loggerLevel(r.l)
+}
+
+func (r *RateLimiter) With(keyvals ...any) Logger {
+	return newRateLimiter(r.l.With(keyvals...), r.state)
+}
+
+func (r *RateLimiter) Sync() error {
+	return r.l.Sync()
+}
+
+func (r *RateLimiter) enabled(level Level) bool {
+	return loggerEnabled(r.l, level)
+}
+
+func (r *RateLimiter) output(level Level, msg string, fields []Field) {
+	if b := r.state.buckets[levelIndex(level)]; b != nil && level < LevelPanic && !b.allow(r.state.now()) {
+		r.state.drop(level)
+		return
+	}
+	forceLog(r.l, level, msg, fields)
+}
+
+func (r *RateLimiter) exit() {
+	exitLogger(r.l)
+}
@@ -0,0 +1,136 @@
+package ulog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewSampler(NewStdLogger(&buf), SamplerConfig{Interval: time.Second, First: 2, Thereafter: 3})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.state.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		s.Info("hot")
+	}
+	s.With("a", 1).Info("other")
+	// 1, 2 and then 5 and 8 are logged.
+	if got := strings.Count(buf.String(), " hot\n"); got != 4 {
+		t.Errorf("Sampler: got %v records, want 4", got)
+	}
+	if got := s.Dropped(LevelInfo); got != 6 {
+		t.Errorf("Dropped(): got %v, want 6", got)
+	}
+	if !strings.Contains(buf.String(), " other a=1\n") {
+		t.Errorf("Sampler: got %q, want other message", buf.String())
+	}
+
+	buf.Reset()
+	now = now.Add(time.Second)
+	s.Info("hot")
+	s.Warn("hot")
+	s.Debugf("hidden %d", 1)
+	if got := strings.Count(buf.String(), " hot\n"); got != 2 {
+		t.Errorf("Sampler: got %v records after interval, want 2", got)
+	}
+	if got := s.TotalDropped(); got != 6 {
+		t.Errorf("TotalDropped(): got %v, want 6", got)
+	}
+}
+
+func TestSamplerFatal(t *testing.T) {
+	var buf bytes.Buffer
+	exits := 0
+	s := NewSampler(NewStdLogger(&buf, WithExitFunc(func(int) { exits++ })), SamplerConfig{First: 1})
+	for i := 0; i < 3; i++ {
+		s.Fatal("fatal")
+	}
+	if got := strings.Count(buf.String(), "[FATAL]"); got != 3 || exits != 3 {
+		t.Errorf("Fatal(): got %v records and %v exits, want 3 and 3", got, exits)
+	}
+}
+
+func TestSamplerConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewSampler(NewStdLogger(&buf), SamplerConfig{Interval: time.Hour, First: 10, Thereafter: 100})
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l := s.With("k", "v")
+			for j := 0; j < 100; j++ {
+				l.Info("concurrent")
+			}
+		}()
+	}
+	wg.Wait()
+	logged := uint64(strings.Count(buf.String(), "\n"))
+	if logged+s.Dropped(LevelInfo) != 5000 {
+		t.Errorf("Sampler: got %v logged and %v dropped, want 5000 in total", logged, s.Dropped(LevelInfo))
+	}
+	if logged != 10+(5000-10)/100 {
+		t.Errorf("Sampler: got %v logged, want %v", logged, 10+(5000-10)/100)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRateLimiter(NewStdLogger(&buf), map[Level]RateLimit{LevelInfo: {Rate: 2, Burst: 3}})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.state.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		r.Info("limited")
+		r.Warn("unlimited")
+	}
+	now = now.Add(time.Second)
+	for i := 0; i < 5; i++ {
+		r.With("a", 1).Infof("limited %d", i)
+	}
+	if got := strings.Count(buf.String(), " limited"); got != 5 {
+		t.Errorf("RateLimiter: got %v records, want 5", got)
+	}
+	if got := strings.Count(buf.String(), " unlimited"); got != 5 {
+		t.Errorf("RateLimiter: got %v unlimited records, want 5", got)
+	}
+	if got := r.Dropped(LevelInfo); got != 5 || r.TotalDropped() != 5 {
+		t.Errorf("Dropped(): got %v, want 5", got)
+	}
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit RateLimit
+		want  int
+	}{
+		{"zero burst", RateLimit{Rate: 1000}, 5},
+		{"fractional rate", RateLimit{Rate: 2.5}, 3},
+		{"slow rate", RateLimit{Rate: 0.1}, 1},
+		{"burst", RateLimit{Rate: 1000, Burst: 2}, 2},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		r := NewRateLimiter(NewStdLogger(&buf), map[Level]RateLimit{LevelInfo: tt.limit})
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		r.state.now = func() time.Time { return now }
+		for i := 0; i < 5; i++ {
+			r.Info("limited")
+		}
+		if got := strings.Count(buf.String(), " limited"); got != tt.want {
+			t.Errorf("NewRateLimiter(): name %v , got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewRateLimiter(): want panic for a negative burst")
+		}
+	}()
+	NewRateLimiter(NewStdLogger(&bytes.Buffer{}), map[Level]RateLimit{LevelInfo: {Rate: 1, Burst: -1}})
+}
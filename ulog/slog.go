@@ -0,0 +1,245 @@
+//go:build go1.21
+
+package ulog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// ToSlogLevel converts a Level to a slog.Level: Trace is slog.LevelDebug-4,
+// Debug to Error map to their slog levels, Panic is slog.LevelError+4 and
+// Fatal is slog.LevelError+8.
+func ToSlogLevel(level Level) slog.Level {
+	return slog.Level((int(level) - 1) * 4)
+}
+
+// FromSlogLevel converts a slog.Level to the Level it falls into, e.g.
+// slog.LevelInfo+2 => LevelInfo. It is the inverse of ToSlogLevel.
+func FromSlogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelDebug:
+		return LevelTrace
+	case level >= ToSlogLevel(LevelFatal):
+		return LevelFatal
+	}
+	return Level(int(level-slog.LevelDebug)/4) + LevelDebug
+}
+
+type slogGroup struct {
+	name   string
+	fields []Field
+}
+
+// slogHandler is a slog.Handler that writes through a ulog encoder and writer.
+type slogHandler struct {
+	core   *core
+	level  *levelVar
+	fields []Field
+	groups []slogGroup
+}
+
+var _ slog.Handler = (*slogHandler)(nil)
+
+// NewSlogHandler returns a slog.Handler that encodes records with the
+// configured ulog encoder and writes them to w, like NewStdLogger.
+// e.g. slog.New(NewSlogHandler(os.Stderr, WithEncoder(NewJSONEncoder(EncoderConfig{}))))
+func NewSlogHandler(w io.Writer, opts ...Option) slog.Handler {
+	o := newOptions(opts)
+	h := &slogHandler{
+		core:  newCore(w, o),
+		level: new(levelVar),
+	}
+	h.level.Store(o.level)
+	return h
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return FromSlogLevel(level) >= h.level.Load()
+}
+
+// Handle writes r with the fields of the registered context extractors for ctx.
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make([]Field, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, a)
+		return true
+	})
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		g := h.groups[i]
+		if len(g.fields)+len(fields) == 0 {
+			continue
+		}
+		group := make([]Field, 0, len(g.fields)+len(fields))
+		group = append(group, g.fields...)
+		group = append(group, fields...)
+		fields = []Field{{Key: g.name, Value: group}}
+	}
+	rec := Record{
+		Time:    r.Time,
+		Level:   FromSlogLevel(r.Level),
+		Message: r.Message,
+		Fields:  make([]Field, 0, len(h.fields)+len(fields)),
+	}
+	rec.Fields = append(rec.Fields, h.fields...)
+	rec.Fields = append(rec.Fields, Fields(contextFields(ctx)...)...)
+	rec.Fields = append(rec.Fields, fields...)
+	h.core.addCaller(&rec, r.PC)
+	h.core.write(&rec)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	var fields []Field
+	for _, a := range attrs {
+		fields = appendAttr(fields, a)
+	}
+	if n := len(h.groups); n > 0 {
+		h2.groups = append([]slogGroup(nil), h.groups...)
+		g := &h2.groups[n-1]
+		g.fields = append(append([]Field(nil), g.fields...), fields...)
+	} else {
+		h2.fields = append(append([]Field(nil), h.fields...), fields...)
+	}
+	return &h2
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(append([]slogGroup(nil), h.groups...), slogGroup{name: name})
+	return &h2
+}
+
+// appendAttr appends a as a field. Empty attrs are dropped and groups
+// without a key are inlined.
+func appendAttr(fields []Field, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return append(fields, Field{Key: a.Key, Value: fieldValue(slogValue(a.Value))})
+	}
+	var group []Field
+	for _, ga := range a.Value.Group() {
+		group = appendAttr(group, ga)
+	}
+	if len(group) == 0 {
+		return fields
+	}
+	if a.Key == "" {
+		return append(fields, group...)
+	}
+	return append(fields, Field{Key: a.Key, Value: group})
+}
+
+func slogValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration()
+	case slog.KindTime:
+		return v.Time()
+	}
+	return v.Any()
+}
+
+// slogLogger is a Logger that writes to a slog.Handler.
+type slogLogger struct {
+	leveled
+	h     slog.Handler
+	level *levelVar
+	exitf func(code int)
+}
+
+// NewSlogLogger returns a Logger that writes records to h. Its level, LevelTrace
+// by default, filters records in addition to h.Enabled. Of the options only
+// WithLevel and WithExitFunc apply.
+func NewSlogLogger(h slog.Handler, opts ...Option) Logger {
+	o := newOptions(append([]Option{WithLevel(LevelTrace)}, opts...))
+	l := &slogLogger{
+		h:     h,
+		level: new(levelVar),
+		exitf: o.exit,
+	}
+	l.level.Store(o.level)
+	l.leveled = leveled{l}
+	return l
+}
+
+func (l *slogLogger) SetLevel(level Level) {
+	l.level.Store(level)
+}
+
+func (l *slogLogger) getLevel() Level {
+	return l.level.Load()
+}
+
+func (l *slogLogger) With(keyvals ...any) Logger {
+	fields := Fields(keyvals...)
+	if len(fields) == 0 {
+		return l
+	}
+	l2 := *l
+	l2.h = l.h.WithAttrs(fieldsToAttrs(fields))
+	l2.leveled = leveled{&l2}
+	return &l2
+}
+
+func (l *slogLogger) Sync() error {
+	return nil
+}
+
+func (l *slogLogger) enabled(level Level) bool {
+	return level >= l.level.Load() && l.h.Enabled(context.Background(), ToSlogLevel(level))
+}
+
+func (l *slogLogger) output(level Level, msg string, fields []Field) {
+	var pc uintptr
+	if pcs := callerPCs(0); len(pcs) > 0 {
+		pc = pcs[0]
+	}
+	r := slog.NewRecord(time.Now(), ToSlogLevel(level), msg, pc)
+	r.AddAttrs(fieldsToAttrs(fields)...)
+	_ = l.h.Handle(context.Background(), r)
+}
+
+func (l *slogLogger) exit() {
+	l.exitf(1)
+}
+
+// fieldsToAttrs converts fields to attrs, groups to slog groups. Redacter
+// values are replaced, as the handler does not know about them.
+func fieldsToAttrs(fields []Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		switch v := f.Value.(type) {
+		case []Field:
+			attrs = append(attrs, slog.Attr{Key: f.Key, Value: slog.GroupValue(fieldsToAttrs(v)...)})
+			continue
+		case Redacter:
+			attrs = append(attrs, slog.Any(f.Key, redacted(v)))
+			continue
+		}
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	return attrs
+}
@@ -0,0 +1,161 @@
+//go:build go1.21
+
+package ulog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+)
+
+func TestSlogLevel(t *testing.T) {
+	tests := []struct {
+		name  string
+		level Level
+		slog  slog.Level
+	}{
+		{"trace", LevelTrace, slog.LevelDebug - 4},
+		{"debug", LevelDebug, slog.LevelDebug},
+		{"info", LevelInfo, slog.LevelInfo},
+		{"warn", LevelWarn, slog.LevelWarn},
+		{"error", LevelError, slog.LevelError},
+		{"panic", LevelPanic, slog.LevelError + 4},
+		{"fatal", LevelFatal, slog.LevelError + 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToSlogLevel(tt.level); got != tt.slog {
+				t.Errorf("ToSlogLevel(): name %v , got %v, want %v", tt.name, got, tt.slog)
+			}
+			if got := FromSlogLevel(tt.slog); got != tt.level {
+				t.Errorf("FromSlogLevel(): name %v , got %v, want %v", tt.name, got, tt.level)
+			}
+			if got := FromSlogLevel(tt.slog + 1); got != tt.level {
+				t.Errorf("FromSlogLevel(): name %v , got %v, want %v", tt.name, got, tt.level)
+			}
+		})
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewSlogHandler(&buf, WithEncoder(NewJSONEncoder(EncoderConfig{TimeKey: OmitKey})), WithLevel(LevelInfo))
+	l := slog.New(h).With("a", 1).WithGroup("g").With("b", 2)
+	l.Debug("hidden")
+	l.Info("hello", "c", 3, slog.Group("h", "d", 4), slog.Group("empty"))
+	l.Error("failed", "err", errors.New("boom"))
+	want := `{"level":"INFO","msg":"hello","a":1,"g":{"b":2,"c":3,"h":{"d":4}}}` + "\n" +
+		`{"level":"ERROR","msg":"failed","a":1,"g":{"b":2,"err":"boom"}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("NewSlogHandler(): got %v, want %v", got, want)
+	}
+}
+
+func TestSlogHandlerContext(t *testing.T) {
+	resetExtractors(t)
+	RegisterContextExtractor(ContextValue(RequestIDKey, requestIDKey{}))
+	var buf bytes.Buffer
+	l := slog.New(NewSlogHandler(&buf, WithEncoder(NewTextEncoder(EncoderConfig{TimeKey: OmitKey}))))
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+	l.InfoContext(ctx, "hello", "a", 1)
+	if want := "level=INFO msg=hello request_id=req-1 a=1\n"; buf.String() != want {
+		t.Errorf("Handle(): got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestSlogHandlerConformance(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewSlogHandler(&buf, WithEncoder(NewJSONEncoder(EncoderConfig{})))
+	results := func() []map[string]any {
+		var ms []map[string]any
+		for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+			if len(line) == 0 {
+				continue
+			}
+			var m map[string]any
+			if err := json.Unmarshal(line, &m); err != nil {
+				t.Fatalf("json.Unmarshal(): %v", err)
+			}
+			ms = append(ms, m)
+		}
+		return ms
+	}
+	if err := slogtest.TestHandler(h, results); err != nil {
+		t.Errorf("slogtest.TestHandler(): %v", err)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug - 4,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	l := NewSlogLogger(h).With("a", 1, Group("g", "b", 2))
+	l.Trace("trace")
+	l.Log(LevelWarn, MessageKey, "warn", "err", errors.New("boom"))
+	l.SetLevel(LevelError)
+	l.Info("hidden")
+	want := `{"level":"DEBUG-4","msg":"trace","a":1,"g":{"b":2}}` + "\n" +
+		`{"level":"WARN","msg":"warn","a":1,"g":{"b":2},"err":"boom"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("NewSlogLogger(): got %v, want %v", got, want)
+	}
+}
+
+func TestSlogLoggerRedacter(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	l := NewSlogLogger(h).With("token", secret("abc"))
+	l.Log(LevelInfo, MessageKey, "login", "pw", secret("hunter2"), Group("user", "name", "bob", "pw", secret("hunter2")))
+	want := `{"level":"INFO","msg":"login","token":"secret(a...)","pw":"secret(h...)","user":{"name":"bob","pw":"secret(h...)"}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("NewSlogLogger(): got %v, want %v", got, want)
+	}
+}
+
+func TestSlogLoggerFatal(t *testing.T) {
+	var buf bytes.Buffer
+	code := 0
+	l := NewSlogLogger(slog.NewTextHandler(&buf, nil), WithExitFunc(func(c int) { code = c }))
+	l.Fatalf("fatal %d", 1)
+	if code != 1 || !bytes.Contains(buf.Bytes(), []byte(`level=ERROR+8 msg="fatal 1"`)) {
+		t.Errorf("Fatalf(): got %q code %v", buf.String(), code)
+	}
+}
+
+func TestSlogCaller(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewSlogHandler(&buf, WithCaller(true)))
+	want := line() + 1
+	l.Info("hello")
+	if w := fmt.Sprintf(" ulog/slog_test.go:%d hello\n", want); !strings.HasSuffix(buf.String(), w) {
+		t.Errorf("NewSlogHandler(): got %q, want suffix %q", buf.String(), w)
+	}
+
+	buf.Reset()
+	ul := NewSlogLogger(slog.NewTextHandler(&buf, &slog.HandlerOptions{AddSource: true}))
+	want = line() + 1
+	ul.Info("hello")
+	if w := fmt.Sprintf("slog_test.go:%d msg=hello", want); !strings.Contains(buf.String(), w) {
+		t.Errorf("NewSlogLogger(): got %q, want %q", buf.String(), w)
+	}
+}
@@ -0,0 +1,328 @@
+package ulog
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Facility is a syslog facility.
+type Facility int
+
+// Syslog facilities.
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+	FacilityLocal0 Facility = iota + 4
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// Severity is a syslog severity.
+type Severity int
+
+// Syslog severities.
+const (
+	SeverityEmergency Severity = iota
+	SeverityAlert
+	SeverityCritical
+	SeverityError
+	SeverityWarning
+	SeverityNotice
+	SeverityInfo
+	SeverityDebug
+)
+
+// SyslogSeverity returns the syslog severity of level.
+// Trace and debug are debug, panic is critical and fatal is alert.
+func SyslogSeverity(level Level) Severity {
+	switch {
+	case level <= LevelDebug:
+		return SeverityDebug
+	case level == LevelInfo:
+		return SeverityInfo
+	case level == LevelWarn:
+		return SeverityWarning
+	case level == LevelError:
+		return SeverityError
+	case level == LevelPanic:
+		return SeverityCritical
+	case level == LevelFatal:
+		return SeverityAlert
+	}
+	return SeverityEmergency
+}
+
+// SyslogFormat is the message format of a syslog encoder.
+type SyslogFormat int8
+
+const (
+	// RFC5424 is the syslog protocol format "<PRI>1 TIMESTAMP HOST APP PROCID MSGID - MSG".
+	RFC5424 SyslogFormat = iota
+	// RFC3164 is the BSD syslog format "<PRI>Mmm dd hh:mm:ss HOST APP[PROCID]: MSG".
+	RFC3164
+)
+
+// SyslogConfig configures NewSyslogEncoder.
+type SyslogConfig struct {
+	Format SyslogFormat
+	// Facility is the facility of the messages, FacilityUser if zero since
+	// programs cannot log as the kernel.
+	Facility Facility
+	// Hostname is the host of the messages, os.Hostname by default.
+	Hostname string
+	// AppName is the application of the messages, the base name of the program by default.
+	AppName string
+	// ProcID is the process of the messages, the process id by default.
+	ProcID string
+	// MsgID is the RFC 5424 type of the messages, empty by default.
+	MsgID string
+}
+
+// syslogEncoder writes records as syslog messages. The MSG part is the
+// message followed by the fields in logfmt style, on a single line.
+type syslogEncoder struct {
+	cfg SyslogConfig
+}
+
+// NewSyslogEncoder returns an Encoder that writes records as syslog messages
+// with the severity of their level, for a SyslogWriter.
+// e.g. NewStdLogger(w, WithEncoder(NewSyslogEncoder(SyslogConfig{Facility: FacilityLocal0})))
+func NewSyslogEncoder(cfg SyslogConfig) Encoder {
+	if cfg.Facility == FacilityKern {
+		cfg.Facility = FacilityUser
+	}
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = filepath.Base(os.Args[0])
+	}
+	if cfg.ProcID == "" {
+		cfg.ProcID = strconv.Itoa(os.Getpid())
+	}
+	return &syslogEncoder{cfg: cfg}
+}
+
+func (e *syslogEncoder) Encode(buf *bytes.Buffer, r *Record) error {
+	b := make([]byte, 0, 256)
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(e.cfg.Facility)*8+int64(SyslogSeverity(r.Level)), 10)
+	b = append(b, '>')
+	if e.cfg.Format == RFC3164 {
+		t := r.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+		b = t.AppendFormat(b, time.Stamp)
+		b = append(b, ' ')
+		b = appendSyslogHeader(b, e.cfg.Hostname, 255)
+		b = append(b, ' ')
+		b = appendSyslogHeader(b, e.cfg.AppName, 48)
+		b = append(b, '[')
+		b = appendSyslogHeader(b, e.cfg.ProcID, 128)
+		b = append(b, "]: "...)
+	} else {
+		b = append(b, "1 "...)
+		if r.Time.IsZero() {
+			b = append(b, '-')
+		} else {
+			b = r.Time.AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+		}
+		for _, h := range [...]struct {
+			s string
+			n int
+		}{{e.cfg.Hostname, 255}, {e.cfg.AppName, 48}, {e.cfg.ProcID, 128}, {e.cfg.MsgID, 32}} {
+			b = append(b, ' ')
+			b = appendSyslogHeader(b, h.s, h.n)
+		}
+		b = append(b, " - "...)
+	}
+	b = append(b, r.Message...)
+	var fields []Field
+	if r.Caller != "" {
+		fields = append(fields, Field{Key: CallerKey, Value: r.Caller})
+	}
+	if r.Function != "" {
+		fields = append(fields, Field{Key: FunctionKey, Value: r.Function})
+	}
+	fields = append(fields, r.Fields...)
+	if r.Stack != "" {
+		fields = append(fields, Field{Key: StackKey, Value: r.Stack})
+	}
+	b = appendFields(b, "", fields)
+	b = append(b, '\n')
+	buf.Write(b)
+	return nil
+}
+
+// appendSyslogHeader appends a header field of printable ASCII of at most
+// n bytes, "-" if s is empty.
+func appendSyslogHeader(b []byte, s string, n int) []byte {
+	if s == "" {
+		return append(b, '-')
+	}
+	if len(s) > n {
+		s = s[:n]
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '!' || c > '~' {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// SyslogFraming is how messages are delimited on stream connections.
+type SyslogFraming int8
+
+const (
+	// FramingOctetCounting prefixes each message with its length, see RFC 6587.
+	FramingOctetCounting SyslogFraming = iota
+	// FramingNewline ends each message with a newline, for old RFC 3164 collectors.
+	FramingNewline
+)
+
+// SyslogWriterConfig configures NewSyslogWriter.
+type SyslogWriterConfig struct {
+	// Network is "udp", "tcp", "unix" or "unixgram". If Network and Addr are
+	// empty the local syslog socket is used, such as /dev/log.
+	Network string
+	Addr    string
+	// Framing is the framing of messages on "tcp" and "unix" connections.
+	Framing SyslogFraming
+	// Timeout is the timeout of dialing and of each write, 5s by default.
+	Timeout time.Duration
+}
+
+// syslogSockets are the local syslog sockets tried in order.
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogWriter is an io.Writer that sends each write as one syslog message.
+// It redials once if a write fails. It is safe for concurrent use.
+type SyslogWriter struct {
+	cfg SyslogWriterConfig
+
+	mu      sync.Mutex
+	conn    net.Conn
+	stream  bool
+	framing SyslogFraming
+	closed  bool
+}
+
+// NewSyslogWriter dials the syslog collector of cfg.
+// e.g. NewSyslogWriter(SyslogWriterConfig{Network: "udp", Addr: "logs.example.com:514"})
+func NewSyslogWriter(cfg SyslogWriterConfig) (*SyslogWriter, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	w := &SyslogWriter{cfg: cfg}
+	if err := w.dial(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *SyslogWriter) dial() error {
+	if w.cfg.Network != "" || w.cfg.Addr != "" {
+		conn, err := net.DialTimeout(w.cfg.Network, w.cfg.Addr, w.cfg.Timeout)
+		if err != nil {
+			return err
+		}
+		w.setConn(conn, strings.HasPrefix(w.cfg.Network, "tcp") || w.cfg.Network == "unix", w.cfg.Framing)
+		return nil
+	}
+	for _, path := range syslogSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.DialTimeout(network, path, w.cfg.Timeout); err == nil {
+				// Local stream sockets expect messages ending with a newline.
+				w.setConn(conn, network == "unix", FramingNewline)
+				return nil
+			}
+		}
+	}
+	return errors.New("ulog: no local syslog socket")
+}
+
+func (w *SyslogWriter) setConn(conn net.Conn, stream bool, framing SyslogFraming) {
+	w.conn = conn
+	w.stream = stream
+	w.framing = framing
+}
+
+// Write sends p as one message, without its trailing newline.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	msg := bytes.TrimRight(p, "\n")
+	if w.stream && w.framing == FramingNewline {
+		msg = append(msg[:len(msg):len(msg)], '\n')
+	} else if w.stream {
+		framed := strconv.AppendInt(make([]byte, 0, len(msg)+8), int64(len(msg)), 10)
+		framed = append(framed, ' ')
+		msg = append(framed, msg...)
+	}
+	if w.conn == nil {
+		if err := w.dial(); err != nil {
+			return 0, err
+		}
+	}
+	if err := w.send(msg); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		if err := w.dial(); err != nil {
+			return 0, err
+		}
+		if err := w.send(msg); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *SyslogWriter) send(msg []byte) error {
+	if err := w.conn.SetWriteDeadline(time.Now().Add(w.cfg.Timeout)); err != nil {
+		return err
+	}
+	_, err := w.conn.Write(msg)
+	return err
+}
+
+// Close closes the connection.
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
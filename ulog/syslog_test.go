@@ -0,0 +1,166 @@
+package ulog
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogSeverity(t *testing.T) {
+	tests := []struct {
+		level Level
+		want  Severity
+	}{
+		{LevelTrace, SeverityDebug},
+		{LevelDebug, SeverityDebug},
+		{LevelInfo, SeverityInfo},
+		{LevelWarn, SeverityWarning},
+		{LevelError, SeverityError},
+		{LevelPanic, SeverityCritical},
+		{LevelFatal, SeverityAlert},
+		{Level(9), SeverityEmergency},
+	}
+	for _, tt := range tests {
+		if got := SyslogSeverity(tt.level); got != tt.want {
+			t.Errorf("SyslogSeverity(): name %v , got %v, want %v", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestSyslogEncoder(t *testing.T) {
+	ts := time.Date(2022, 3, 4, 5, 6, 7, 123456789, time.UTC)
+	r := &Record{Time: ts, Level: LevelWarn, Message: "disk low", Caller: "app/main.go:10", Fields: []Field{{"free", "1 GB"}}}
+	tests := []struct {
+		name string
+		cfg  SyslogConfig
+		r    *Record
+		want string
+	}{
+		{"rfc5424", SyslogConfig{Facility: FacilityLocal0, Hostname: "host", AppName: "app", ProcID: "42", MsgID: "DISK"}, r,
+			`<132>1 2022-03-04T05:06:07.123456Z host app 42 DISK - disk low caller=app/main.go:10 free="1 GB"`},
+		{"rfc5424 nil values", SyslogConfig{Hostname: "my host", AppName: "app", ProcID: "1"}, &Record{Level: LevelFatal, Message: "bye"},
+			`<9>1 - my_host app 1 - - bye`},
+		{"rfc3164", SyslogConfig{Format: RFC3164, Facility: FacilityDaemon, Hostname: "host", AppName: "app", ProcID: "42"}, r,
+			`<28>Mar  4 05:06:07 host app[42]: disk low caller=app/main.go:10 free="1 GB"`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := NewSyslogEncoder(tt.cfg).Encode(&buf, tt.r); err != nil {
+			t.Fatalf("Encode(): %v", err)
+		}
+		if got := buf.String(); got != tt.want+"\n" {
+			t.Errorf("NewSyslogEncoder(): name %v , got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func newSyslogLogger(t *testing.T, cfg SyslogWriterConfig) Logger {
+	w, err := NewSyslogWriter(cfg)
+	if err != nil {
+		t.Fatalf("NewSyslogWriter(): %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return NewStdLogger(w, WithEncoder(NewSyslogEncoder(SyslogConfig{Hostname: "host", AppName: "app", ProcID: "1"})))
+}
+
+func TestSyslogWriterUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp: %v", err)
+	}
+	defer pc.Close()
+	l := newSyslogLogger(t, SyslogWriterConfig{Network: "udp", Addr: pc.LocalAddr().String()})
+	l.Error("failed\nat step 2")
+
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	p := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(p)
+	if err != nil {
+		t.Fatalf("ReadFrom(): %v", err)
+	}
+	if got := string(p[:n]); !strings.HasPrefix(got, "<11>1 ") || !strings.HasSuffix(got, " host app 1 - - failed\nat step 2") {
+		t.Errorf("SyslogWriter: got %q", got)
+	}
+}
+
+func TestSyslogWriterStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+		framing SyslogFraming
+	}{
+		{"tcp octet counting", "tcp", FramingOctetCounting},
+		{"tcp newline", "tcp", FramingNewline},
+		{"unix", "unix", FramingOctetCounting},
+	}
+	for _, tt := range tests {
+		addr := "127.0.0.1:0"
+		if tt.network == "unix" {
+			addr = filepath.Join(t.TempDir(), "syslog.sock")
+		}
+		ln, err := net.Listen(tt.network, addr)
+		if err != nil {
+			t.Skipf("%s: %v", tt.network, err)
+		}
+		l := newSyslogLogger(t, SyslogWriterConfig{Network: tt.network, Addr: ln.Addr().String(), Framing: tt.framing})
+		l.Info("one")
+		l.Info("two")
+
+		conn, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("Accept(): %v", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		rd := bufio.NewReader(conn)
+		var msgs []string
+		for i := 0; i < 2; i++ {
+			var msg string
+			if tt.framing == FramingNewline {
+				msg, err = rd.ReadString('\n')
+				msg = strings.TrimSuffix(msg, "\n")
+			} else {
+				var size string
+				if size, err = rd.ReadString(' '); err == nil {
+					n, _ := strconv.Atoi(strings.TrimSpace(size))
+					b := make([]byte, n)
+					_, err = io.ReadFull(rd, b)
+					msg = string(b)
+				}
+			}
+			if err != nil {
+				t.Fatalf("%s: read: %v", tt.name, err)
+			}
+			msgs = append(msgs, msg)
+		}
+		if !strings.HasSuffix(msgs[0], " - - one") || !strings.HasSuffix(msgs[1], " - - two") {
+			t.Errorf("SyslogWriter: name %v , got %q", tt.name, msgs)
+		}
+		conn.Close()
+		ln.Close()
+	}
+}
+
+func TestSyslogWriterClosed(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp: %v", err)
+	}
+	defer pc.Close()
+	w, err := NewSyslogWriter(SyslogWriterConfig{Network: "udp", Addr: pc.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("NewSyslogWriter(): %v", err)
+	}
+	w.Close()
+	if _, err := w.Write([]byte("late\n")); err != ErrClosed {
+		t.Errorf("Write(): got %v, want %v", err, ErrClosed)
+	}
+	if _, err := NewSyslogWriter(SyslogWriterConfig{Network: "unix", Addr: filepath.Join(t.TempDir(), "none.sock")}); err == nil {
+		t.Errorf("NewSyslogWriter(): got nil error for a missing socket")
+	}
+}
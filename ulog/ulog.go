@@ -1,27 +1,19 @@
 package ulog
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"log"
+	"os"
+	"sync"
+	"time"
 )
 
-// Level is a logger level.
-type Level int8
-
 const LevelKey = "level"
 
-const (
-	// LevelDebug is logger debug level.
-	LevelDebug Level = iota
-	// LevelInfo is logger info level.
-	LevelInfo
-	// LevelWarn is logger warn level.
-	LevelWarn
-	// LevelError is logger error level.
-	LevelError
-)
-
+// The prefixes below are the DefaultTheme colored level tags written by the
+// default encoder. Colors are only written if the logger's ColorMode allows it.
 const (
 	// LevelDebugPrefix is logger debug prefix. color: blue
 	DebugPrefix = "\033[34m[DEBUG]\033[0m "
@@ -33,8 +25,18 @@ const (
 	ErrorPrefix = "\033[31m[ERROR]\033[0m "
 )
 
+// Logger is a leveled logger with structured fields.
 type Logger interface {
 	SetLevel(level Level)
+	// Log logs alternating keys and values at the given level.
+	// The value of MessageKey, if present, is used as the message.
+	Log(level Level, keyvals ...any)
+	// With returns a child logger that adds the given keys and values to every record.
+	With(keyvals ...any) Logger
+	// Sync flushes buffered records to the writers.
+	Sync() error
+	Trace(v ...any)
+	Tracef(format string, v ...any)
 	Debug(v ...any)
 	Debugf(format string, v ...any)
 	Info(v ...any)
@@ -43,11 +45,172 @@ type Logger interface {
 	Warnf(format string, v ...any)
 	Error(v ...any)
 	Errorf(format string, v ...any)
+	// Panic logs at LevelPanic and then panics with the message.
+	Panic(v ...any)
+	Panicf(format string, v ...any)
+	// Fatal logs at LevelFatal, syncs the writers and then calls the exit function.
+	Fatal(v ...any)
+	Fatalf(format string, v ...any)
+}
+
+// Option configures a logger created by NewStdLogger.
+type Option func(*options)
+
+type options struct {
+	level   Level
+	encoder Encoder
+	color   ColorMode
+	theme   Theme
+	exit    func(code int)
+	redact  RedactConfig
+	hooks   []hookEntry
+
+	caller     bool
+	function   bool
+	skip       int
+	stackLevel Level
+
+	handleError func(err error)
+}
+
+// WithLevel sets the initial level of the logger. The default is LevelDebug.
+func WithLevel(level Level) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithEncoder sets the encoder of the logger, e.g. NewJSONEncoder or NewTextEncoder.
+// The default encoder writes "[INFO ] 2006/01/02 15:04:05 msg key=value" lines.
+func WithEncoder(enc Encoder) Option {
+	return func(o *options) {
+		o.encoder = enc
+	}
+}
+
+// WithExitFunc sets the function called by Fatal and Fatalf. The default is os.Exit.
+func WithExitFunc(exit func(code int)) Option {
+	return func(o *options) {
+		o.exit = exit
+	}
+}
+
+// WithCaller adds the short file:line of the log call to every record.
+func WithCaller(enabled bool) Option {
+	return func(o *options) {
+		o.caller = enabled
+	}
+}
+
+// WithCallerFunction adds the function name of the log call to every record.
+func WithCallerFunction(enabled bool) Option {
+	return func(o *options) {
+		o.function = enabled
+	}
+}
+
+// WithCallerSkip skips skip more frames when finding the caller, for
+// libraries that wrap the logger. Frames of this package are always skipped.
+func WithCallerSkip(skip int) Option {
+	return func(o *options) {
+		o.skip = skip
+	}
+}
+
+// WithStacktrace adds a stack trace to records at or above level.
+func WithStacktrace(level Level) Option {
+	return func(o *options) {
+		o.stackLevel = level
+	}
+}
+
+// WithColor sets when the encoder colors its output. The default is ColorAuto.
+func WithColor(mode ColorMode) Option {
+	return func(o *options) {
+		o.color = mode
+	}
+}
+
+// WithTheme sets the colors used for each level. The default is DefaultTheme.
+func WithTheme(theme Theme) Option {
+	return func(o *options) {
+		o.theme = theme
+	}
+}
+
+// core is the output shared by a logger and the children created by With.
+type core struct {
+	mu     sync.Mutex
+	w      io.Writer
+	enc    Encoder
+	exit   func(code int)
+	redact *redactor
+	hooks  []hookEntry
+
+	caller     bool
+	function   bool
+	skip       int
+	stackLevel Level
+
+	handleError func(err error)
+}
+
+var bufPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
 }
 
+// write encodes r and writes it to the writer with a single Write call.
+func (c *core) write(r *Record) {
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		// Don't keep large buffers in the pool.
+		if buf.Cap() <= 64<<10 {
+			bufPool.Put(buf)
+		}
+	}()
+	c.redact.record(r)
+	if len(c.hooks) > 0 {
+		n := len(r.Fields)
+		c.fireHooks(r)
+		// Fields added by hooks, such as for enrichment, are redacted too.
+		if len(r.Fields) > n {
+			if added, changed := c.redact.fields(r.Fields[n:]); changed {
+				r.Fields = append(r.Fields[:n:n], added...)
+			}
+		}
+	}
+	if err := c.enc.Encode(buf, r); err != nil {
+		c.handleError(fmt.Errorf("ulog: encode record: %w", err))
+		return
+	}
+	c.mu.Lock()
+	_, err := c.w.Write(buf.Bytes())
+	c.mu.Unlock()
+	if err != nil {
+		c.handleError(fmt.Errorf("ulog: write record: %w", err))
+	}
+}
+
+// sync flushes the writer if it has a Sync method, such as *os.File.
+func (c *core) sync() error {
+	s, ok := c.w.(interface{ Sync() error })
+	if !ok {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return s.Sync()
+}
+
+// stdLogger is safe for concurrent use. Children created by With share
+// the level and the output of their parent.
 type stdLogger struct {
-	log   *log.Logger
-	level Level
+	core   *core
+	level  *levelVar
+	fields []Field
 }
 
 var _ Logger = (*stdLogger)(nil)
@@ -55,76 +218,209 @@ var _ Logger = (*stdLogger)(nil)
 // StdLogger is default logger.
 var StdLogger = NewStdLogger(log.Writer())
 
-func NewStdLogger(w io.Writer) Logger {
-	return &stdLogger{
-		log: log.New(w, "", log.LstdFlags),
+// NewStdLogger returns a logger that writes records encoded by the configured encoder to w.
+func NewStdLogger(w io.Writer, opts ...Option) Logger {
+	o := newOptions(opts)
+	l := &stdLogger{
+		core:  newCore(w, o),
+		level: new(levelVar),
+	}
+	l.level.Store(o.level)
+	return l
+}
+
+// newOptions applies opts over the defaults.
+func newOptions(opts []Option) options {
+	o := options{
+		level:   LevelDebug,
+		encoder: stdEncoder{},
+		theme:   DefaultTheme,
+		exit:    os.Exit,
+		// No stack traces unless WithStacktrace is set.
+		stackLevel: LevelFatal + 1,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// newCore returns the output to w configured by o.
+func newCore(w io.Writer, o options) *core {
+	enc := o.encoder
+	if ce, ok := enc.(colorEncoder); ok && (o.color == ColorAlways || o.color == ColorAuto && ColorEnabled(w)) {
+		enc = ce.withTheme(o.theme)
+	}
+	handleError := o.handleError
+	if handleError == nil {
+		handleError = printError
+	}
+	return &core{
+		w:          w,
+		enc:        enc,
+		exit:       o.exit,
+		redact:     newRedactor(o.redact),
+		hooks:      o.hooks,
+		caller:     o.caller,
+		function:   o.function,
+		skip:       o.skip,
+		stackLevel: o.stackLevel,
+
+		handleError: handleError,
 	}
 }
 
 func (l *stdLogger) SetLevel(level Level) {
-	l.level = level
+	l.level.Store(level)
+}
+
+func (l *stdLogger) getLevel() Level {
+	return l.level.Load()
+}
+
+func (l *stdLogger) enabled(level Level) bool {
+	return level >= l.level.Load()
+}
+
+func (l *stdLogger) Log(level Level, keyvals ...any) {
+	if !l.enabled(level) {
+		return
+	}
+	msg, fields := splitMessage(Fields(keyvals...))
+	l.output(level, msg, fields)
+}
+
+func (l *stdLogger) With(keyvals ...any) Logger {
+	fields := make([]Field, 0, len(l.fields)+len(keyvals)/2)
+	fields = append(fields, l.fields...)
+	fields = append(fields, Fields(keyvals...)...)
+	return &stdLogger{
+		core:   l.core,
+		level:  l.level,
+		fields: fields,
+	}
+}
+
+func (l *stdLogger) Sync() error {
+	return l.core.sync()
+}
+
+func (l *stdLogger) Trace(v ...interface{}) {
+	if !l.enabled(LevelTrace) {
+		return
+	}
+	l.output(LevelTrace, sprint(v...), nil)
+}
+
+func (l *stdLogger) Tracef(format string, v ...interface{}) {
+	if !l.enabled(LevelTrace) {
+		return
+	}
+	l.output(LevelTrace, sprintf(format, v...), nil)
 }
 
 func (l *stdLogger) Debug(v ...interface{}) {
-	if LevelDebug < l.level {
+	if !l.enabled(LevelDebug) {
 		return
 	}
-	l.log.SetPrefix(DebugPrefix)
-	l.log.Output(2, fmt.Sprintln(v...))
+	l.output(LevelDebug, sprint(v...), nil)
 }
 
 func (l *stdLogger) Debugf(format string, v ...interface{}) {
-	if LevelDebug < l.level {
+	if !l.enabled(LevelDebug) {
 		return
 	}
-	l.log.SetPrefix(DebugPrefix)
-	l.log.Output(2, fmt.Sprintf(format, v...))
+	l.output(LevelDebug, sprintf(format, v...), nil)
 }
 
 func (l *stdLogger) Info(v ...interface{}) {
-	if LevelInfo < l.level {
+	if !l.enabled(LevelInfo) {
 		return
 	}
-	l.log.SetPrefix(InfoPrefix)
-	l.log.Output(2, fmt.Sprintln(v...))
+	l.output(LevelInfo, sprint(v...), nil)
 }
 
 func (l *stdLogger) Infof(format string, v ...interface{}) {
-	if LevelInfo < l.level {
+	if !l.enabled(LevelInfo) {
 		return
 	}
-	l.log.SetPrefix(InfoPrefix)
-	l.log.Output(2, fmt.Sprintf(format, v...))
+	l.output(LevelInfo, sprintf(format, v...), nil)
 }
 
 func (l *stdLogger) Warn(v ...interface{}) {
-	if LevelWarn < l.level {
+	if !l.enabled(LevelWarn) {
 		return
 	}
-	l.log.SetPrefix(WarnPrefix)
-	l.log.Output(2, fmt.Sprintln(v...))
+	l.output(LevelWarn, sprint(v...), nil)
 }
 
 func (l *stdLogger) Warnf(format string, v ...interface{}) {
-	if LevelWarn < l.level {
+	if !l.enabled(LevelWarn) {
 		return
 	}
-	l.log.SetPrefix(WarnPrefix)
-	l.log.Output(2, fmt.Sprintf(format, v...))
+	l.output(LevelWarn, sprintf(format, v...), nil)
 }
 
 func (l *stdLogger) Error(v ...interface{}) {
-	if LevelError < l.level {
+	if !l.enabled(LevelError) {
 		return
 	}
-	l.log.SetPrefix(ErrorPrefix)
-	l.log.Output(2, fmt.Sprintln(v...))
+	l.output(LevelError, sprint(v...), nil)
 }
 
 func (l *stdLogger) Errorf(format string, v ...interface{}) {
-	if LevelError < l.level {
+	if !l.enabled(LevelError) {
 		return
 	}
-	l.log.SetPrefix(ErrorPrefix)
-	l.log.Output(2, fmt.Sprintf(format, v...))
+	l.output(LevelError, sprintf(format, v...), nil)
+}
+
+func (l *stdLogger) Panic(v ...interface{}) {
+	l.panic(sprint(v...))
+}
+
+func (l *stdLogger) Panicf(format string, v ...interface{}) {
+	l.panic(sprintf(format, v...))
+}
+
+func (l *stdLogger) panic(msg string) {
+	if l.enabled(LevelPanic) {
+		l.output(LevelPanic, msg, nil)
+	}
+	panic(msg)
+}
+
+func (l *stdLogger) Fatal(v ...interface{}) {
+	l.fatal(sprint(v...))
+}
+
+func (l *stdLogger) Fatalf(format string, v ...interface{}) {
+	l.fatal(sprintf(format, v...))
+}
+
+func (l *stdLogger) fatal(msg string) {
+	if l.enabled(LevelFatal) {
+		l.output(LevelFatal, msg, nil)
+	}
+	l.exit()
+}
+
+// exit syncs the writer and calls the exit function.
+func (l *stdLogger) exit() {
+	_ = l.core.sync()
+	l.core.exit(1)
+}
+
+// output writes a record with the logger fields and the given fields.
+func (l *stdLogger) output(level Level, msg string, fields []Field) {
+	r := Record{
+		Time:    time.Now(),
+		Level:   level,
+		Message: msg,
+		Fields:  make([]Field, 0, len(l.fields)+len(fields)),
+	}
+	r.Fields = append(r.Fields, l.fields...)
+	r.Fields = append(r.Fields, fields...)
+	l.core.addCaller(&r, 0)
+	l.core.write(&r)
 }
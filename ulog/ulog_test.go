@@ -1,7 +1,12 @@
 package ulog
 
 import (
+	"bytes"
+	"errors"
 	"log"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -16,6 +21,17 @@ func TestSetLevel(t *testing.T) {
 	StdLogger.SetLevel(LevelError)
 }
 
+func TestTrace(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(&buf)
+	l.Trace("hidden")
+	l.SetLevel(LevelTrace)
+	l.Tracef("%s", "trace")
+	if got := buf.String(); !strings.HasPrefix(got, "[TRACE] ") || !strings.HasSuffix(got, " trace\n") {
+		t.Errorf("Trace(): got %q", got)
+	}
+}
+
 func TestDebug(t *testing.T) {
 	StdLogger.Debug("debug")
 }
@@ -47,3 +63,173 @@ func TestError(t *testing.T) {
 func TestErrorf(t *testing.T) {
 	StdLogger.Errorf("errorf")
 }
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyvals []any
+		want    []Field
+	}{
+		{"pairs", []any{"a", 1, "b", "x"}, []Field{{"a", 1}, {"b", "x"}}},
+		{"odd", []any{"a", 1, "b"}, []Field{{"a", 1}, {"b", MissingValue}}},
+		{"bad key", []any{1, "a", 2}, []Field{{BadKey, 1}, {"a", 2}}},
+		{"field", []any{Field{"a", 1}, "b", 2}, []Field{{"a", 1}, {"b", 2}}},
+		{"map", []any{"m", map[string]any{"b": 2, "a": map[string]int{"c": 3}}}, []Field{{"m", []Field{{"a", []Field{{"c", 3}}}, {"b", 2}}}}},
+		{"empty", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fields(tt.keyvals...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Fields(): name %v , got %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// nilError has a value receiver, so Error panics on a nil pointer.
+type nilError struct{}
+
+func (nilError) Error() string { return "nil error" }
+
+// panicStringer panics in String.
+type panicStringer struct{}
+
+func (panicStringer) String() string { panic("boom") }
+
+func TestLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   Level
+		keyvals []any
+		want    string
+	}{
+		{"msg", LevelInfo, []any{MessageKey, "hello", "a", 1}, "hello a=1\n"},
+		{"no msg", LevelWarn, []any{"a", "b c"}, `a="b c"` + "\n"},
+		{"error", LevelError, []any{"err", errors.New("boom")}, "err=boom\n"},
+		{"group", LevelInfo, []any{"m", map[string]any{"b": 2, "a": 1}}, "m.a=1 m.b=2\n"},
+		{"odd", LevelInfo, []any{"a"}, "a=" + MissingValue + "\n"},
+		{"nil error", LevelInfo, []any{"err", (*nilError)(nil)}, "err=<nil>\n"},
+		{"nil stringer", LevelInfo, []any{"s", (*panicStringer)(nil)}, "s=<nil>\n"},
+		{"panic", LevelInfo, []any{"s", panicStringer{}}, `s="%!v(PANIC=String method: boom)"` + "\n"},
+		{"filtered", LevelDebug, []any{"a", 1}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := NewStdLogger(&buf)
+			l.SetLevel(LevelInfo)
+			l.Log(tt.level, tt.keyvals...)
+			if got := buf.String(); !strings.HasSuffix(got, tt.want) || (tt.want == "") != (got == "") {
+				t.Errorf("Log(): name %v , got %q, want suffix %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWith(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(&buf).With("service", "api")
+	l.With("id", 1).Info("hello")
+	l.Infof("%s", "world")
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []string{"hello service=api id=1", "world service=api"}
+	if len(lines) != len(want) {
+		t.Fatalf("With(): got %q, want %q", lines, want)
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Errorf("With(): got %q, want suffix %q", lines[i], want[i])
+		}
+	}
+}
+
+// Run with -race to check that levels and writes are synchronized.
+func TestConcurrentLogging(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(&buf)
+	levels := []Level{LevelDebug, LevelInfo, LevelWarn, LevelError}
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			level := levels[i%len(levels)]
+			child := l.With("g", i)
+			for j := 0; j < 20; j++ {
+				child.Log(level, MessageKey, level.String())
+				if j%5 == 0 {
+					l.SetLevel(LevelDebug)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 100*20 {
+		t.Fatalf("Log(): got %v lines, want %v", len(lines), 100*20)
+	}
+	for _, line := range lines {
+		for _, level := range levels {
+			if strings.HasPrefix(line, levelTag(level)) && !strings.Contains(line, " "+level.String()+" g=") {
+				t.Fatalf("Log(): line %q has prefix of level %v", line, level)
+			}
+		}
+	}
+}
+
+func TestConcurrentSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(&buf)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			l.SetLevel(Level(i % 4))
+		}(i)
+		go func() {
+			defer wg.Done()
+			l.Infof("info")
+			l.With("a", 1).Error("error")
+		}()
+	}
+	wg.Wait()
+}
+
+type syncBuffer struct {
+	bytes.Buffer
+	synced bool
+}
+
+func (b *syncBuffer) Sync() error {
+	b.synced = true
+	return nil
+}
+
+func TestFatal(t *testing.T) {
+	var buf syncBuffer
+	code := -1
+	l := NewStdLogger(&buf, WithExitFunc(func(c int) { code = c }))
+	l.With("a", 1).Fatalf("fatal %d", 1)
+	if got := buf.String(); !strings.HasPrefix(got, "[FATAL] ") || !strings.HasSuffix(got, " fatal 1 a=1\n") {
+		t.Errorf("Fatal(): got %q", buf.String())
+	}
+	if !buf.synced || code != 1 {
+		t.Errorf("Fatal(): got synced %v code %v, want true 1", buf.synced, code)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(&buf)
+	defer func() {
+		if r := recover(); r != "panic 1" {
+			t.Errorf("Panic(): got %v, want %v", r, "panic 1")
+		}
+		if !strings.HasPrefix(buf.String(), "[PANIC] ") {
+			t.Errorf("Panic(): got %q", buf.String())
+		}
+	}()
+	l.Panic("panic", 1)
+}
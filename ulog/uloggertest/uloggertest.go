@@ -0,0 +1,254 @@
+package uloggertest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/xbmlz/guc/ulog"
+)
+
+// Entry is a captured record.
+type Entry struct {
+	ulog.Record
+}
+
+// Field returns the value of the field key. Keys of fields in groups are
+// dotted, e.g. "user.id".
+func (e Entry) Field(key string) (any, bool) {
+	return lookup(e.Fields, key)
+}
+
+func lookup(fields []ulog.Field, key string) (any, bool) {
+	for _, f := range fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	for _, f := range fields {
+		if group, ok := f.Value.([]ulog.Field); ok && strings.HasPrefix(key, f.Key+".") {
+			if v, ok := lookup(group, key[len(f.Key)+1:]); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// String returns the entry formatted like the text encoder, without the time.
+func (e Entry) String() string {
+	var buf bytes.Buffer
+	if err := textEncoder.Encode(&buf, &e.Record); err != nil {
+		return fmt.Sprintf("%v %s %v", e.Level, e.Message, e.Fields)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+var textEncoder = ulog.NewTextEncoder(ulog.EncoderConfig{TimeKey: ulog.OmitKey})
+
+// Entries is a list of captured records.
+type Entries []Entry
+
+// Filter returns the entries for which keep returns true.
+func (es Entries) Filter(keep func(Entry) bool) Entries {
+	var out Entries
+	for _, e := range es {
+		if keep(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// FilterLevel returns the entries at level.
+func (es Entries) FilterLevel(level ulog.Level) Entries {
+	return es.Filter(func(e Entry) bool { return e.Level == level })
+}
+
+// FilterMinLevel returns the entries at or above level.
+func (es Entries) FilterMinLevel(level ulog.Level) Entries {
+	return es.Filter(func(e Entry) bool { return e.Level >= level })
+}
+
+// FilterMessage returns the entries whose message contains substr.
+func (es Entries) FilterMessage(substr string) Entries {
+	return es.Filter(func(e Entry) bool { return strings.Contains(e.Message, substr) })
+}
+
+// FilterField returns the entries with the field key equal to value.
+func (es Entries) FilterField(key string, value any) Entries {
+	return es.Filter(func(e Entry) bool {
+		v, ok := e.Field(key)
+		return ok && equal(v, value)
+	})
+}
+
+// Messages returns the messages of the entries.
+func (es Entries) Messages() []string {
+	messages := make([]string, len(es))
+	for i, e := range es {
+		messages[i] = e.Message
+	}
+	return messages
+}
+
+// String returns the entries one per line.
+func (es Entries) String() string {
+	var b strings.Builder
+	for _, e := range es {
+		b.WriteString(e.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// recorder stores the records of a Logger and its children.
+type recorder struct {
+	mu      sync.Mutex
+	entries Entries
+	exits   int
+}
+
+// Encode captures r instead of encoding it.
+func (rec *recorder) Encode(_ *bytes.Buffer, r *ulog.Record) error {
+	e := Entry{*r}
+	e.Fields = append([]ulog.Field(nil), r.Fields...)
+	rec.mu.Lock()
+	rec.entries = append(rec.entries, e)
+	rec.mu.Unlock()
+	return nil
+}
+
+// Logger is a ulog.Logger that captures its records in memory. Loggers
+// created from it with With capture into the same list.
+type Logger struct {
+	ulog.Logger
+	t   testing.TB
+	rec *recorder
+}
+
+// New returns a Logger at LevelTrace that captures the caller of each
+// record. Fatal records are captured without exiting. The options apply
+// after the defaults, except that the encoder cannot be changed.
+// The captured records are printed with t.Log if the test fails.
+func New(t testing.TB, opts ...ulog.Option) *Logger {
+	rec := &recorder{}
+	defaults := []ulog.Option{
+		ulog.WithLevel(ulog.LevelTrace),
+		ulog.WithCaller(true),
+		ulog.WithExitFunc(func(int) {
+			rec.mu.Lock()
+			rec.exits++
+			rec.mu.Unlock()
+		}),
+	}
+	opts = append(append(defaults, opts...), ulog.WithEncoder(rec), ulog.WithColor(ulog.ColorNever))
+	l := &Logger{
+		Logger: ulog.NewStdLogger(io.Discard, opts...),
+		t:      t,
+		rec:    rec,
+	}
+	t.Cleanup(func() {
+		if t.Failed() {
+			l.Dump()
+		}
+	})
+	return l
+}
+
+// Entries returns a copy of the captured records in order.
+func (l *Logger) Entries() Entries {
+	l.rec.mu.Lock()
+	defer l.rec.mu.Unlock()
+	return append(Entries(nil), l.rec.entries...)
+}
+
+// Len returns the number of captured records.
+func (l *Logger) Len() int {
+	l.rec.mu.Lock()
+	defer l.rec.mu.Unlock()
+	return len(l.rec.entries)
+}
+
+// Exits returns the number of Fatal calls.
+func (l *Logger) Exits() int {
+	l.rec.mu.Lock()
+	defer l.rec.mu.Unlock()
+	return l.rec.exits
+}
+
+// Reset removes the captured records.
+func (l *Logger) Reset() {
+	l.rec.mu.Lock()
+	defer l.rec.mu.Unlock()
+	l.rec.entries = nil
+	l.rec.exits = 0
+}
+
+// Dump prints the captured records with t.Log.
+func (l *Logger) Dump() {
+	l.t.Helper()
+	l.t.Logf("captured logs:\n%s", l.Entries())
+}
+
+// AssertLogged fails the test unless a record at level with the message msg
+// and the fields in keyvals was captured. It returns the first such record.
+// e.g. log.AssertLogged(ulog.LevelInfo, "user created", "id", 42)
+func (l *Logger) AssertLogged(level ulog.Level, msg string, keyvals ...any) (Entry, bool) {
+	l.t.Helper()
+	matches := l.matching(level, msg, keyvals)
+	if len(matches) == 0 {
+		l.t.Errorf("AssertLogged(): no %v record %q with %v", level, msg, ulog.Fields(keyvals...))
+		return Entry{}, false
+	}
+	return matches[0], true
+}
+
+// AssertNotLogged fails the test if a record at level with the message msg
+// and the fields in keyvals was captured.
+func (l *Logger) AssertNotLogged(level ulog.Level, msg string, keyvals ...any) bool {
+	l.t.Helper()
+	if matches := l.matching(level, msg, keyvals); len(matches) > 0 {
+		l.t.Errorf("AssertNotLogged(): got %v", matches[0])
+		return false
+	}
+	return true
+}
+
+// AssertCount fails the test unless n records at or above level were captured.
+func (l *Logger) AssertCount(level ulog.Level, n int) bool {
+	l.t.Helper()
+	if got := len(l.Entries().FilterMinLevel(level)); got != n {
+		l.t.Errorf("AssertCount(): got %v records at or above %v, want %v", got, level, n)
+		return false
+	}
+	return true
+}
+
+func (l *Logger) matching(level ulog.Level, msg string, keyvals []any) Entries {
+	fields := ulog.Fields(keyvals...)
+	return l.Entries().Filter(func(e Entry) bool {
+		if e.Level != level || e.Message != msg {
+			return false
+		}
+		for _, f := range fields {
+			if v, ok := e.Field(f.Key); !ok || !equal(v, f.Value) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// equal reports whether a captured value equals want. Values of different
+// types are equal if they format the same, so 42 matches int64(42).
+func equal(got, want any) bool {
+	if reflect.DeepEqual(got, want) {
+		return true
+	}
+	return fmt.Sprint(got) == fmt.Sprint(want)
+}
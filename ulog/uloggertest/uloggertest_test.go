@@ -0,0 +1,107 @@
+package uloggertest
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/xbmlz/guc/ulog"
+)
+
+// fakeT records the failures of assertions instead of failing the test.
+type fakeT struct {
+	testing.TB
+	errors []string
+	logs   []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Logf(format string, args ...any) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+func line() int {
+	_, _, n, _ := runtime.Caller(1)
+	return n
+}
+
+func TestLogger(t *testing.T) {
+	l := New(t)
+	want := line() + 1
+	l.Info("started")
+	l.With("user", map[string]any{"id": 42}).Log(ulog.LevelWarn, ulog.MessageKey, "slow", "ms", 1500)
+	l.Debugf("n=%d", 3)
+	l.Fatal("fatal")
+
+	entries := l.Entries()
+	if got, want := entries.Messages(), []string{"started", "slow", "n=3", "fatal"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Entries(): got %v, want %v", got, want)
+	}
+	if got, want := entries[0].Caller, fmt.Sprintf("uloggertest/uloggertest_test.go:%d", want); got != want {
+		t.Errorf("Entry.Caller: got %v, want %v", got, want)
+	}
+	if got := entries[1].String(); got != "level=WARN msg=slow caller="+entries[1].Caller+" user.id=42 ms=1500" {
+		t.Errorf("Entry.String(): got %v", got)
+	}
+	if v, ok := entries[1].Field("user.id"); !ok || v != 42 {
+		t.Errorf("Entry.Field(): got %v %v, want 42 true", v, ok)
+	}
+	if l.Exits() != 1 {
+		t.Errorf("Exits(): got %v, want 1", l.Exits())
+	}
+
+	tests := []struct {
+		name string
+		got  Entries
+		want []string
+	}{
+		{"level", entries.FilterLevel(ulog.LevelDebug), []string{"n=3"}},
+		{"min level", entries.FilterMinLevel(ulog.LevelWarn), []string{"slow", "fatal"}},
+		{"message", entries.FilterMessage("ar"), []string{"started"}},
+		{"field", entries.FilterField("ms", int64(1500)), []string{"slow"}},
+		{"none", entries.FilterField("ms", 1), []string{}},
+	}
+	for _, tt := range tests {
+		if got := tt.got.Messages(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Filter(): name %v , got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	l.AssertLogged(ulog.LevelWarn, "slow", "user", map[string]any{"id": 42}, "ms", 1500)
+	l.AssertNotLogged(ulog.LevelWarn, "slow", "ms", 1)
+	l.AssertCount(ulog.LevelInfo, 3)
+
+	l.Reset()
+	if l.Len() != 0 || l.Exits() != 0 {
+		t.Errorf("Reset(): got %v records and %v exits", l.Len(), l.Exits())
+	}
+}
+
+func TestLoggerFailures(t *testing.T) {
+	ft := &fakeT{TB: t}
+	l := New(ft, ulog.WithLevel(ulog.LevelInfo))
+	l.Debug("hidden")
+	l.Log(ulog.LevelInfo, ulog.MessageKey, "info", "a", 1)
+
+	l.AssertLogged(ulog.LevelInfo, "info", "a", 2)
+	l.AssertNotLogged(ulog.LevelInfo, "info")
+	l.AssertCount(ulog.LevelTrace, 2)
+	if len(ft.errors) != 3 {
+		t.Fatalf("assertions: got %v failures, want 3: %v", len(ft.errors), ft.errors)
+	}
+	if want := "AssertLogged(): no INFO record \"info\" with [{a 2}]"; ft.errors[0] != want {
+		t.Errorf("AssertLogged(): got %v, want %v", ft.errors[0], want)
+	}
+
+	l.Dump()
+	if len(ft.logs) != 1 || !strings.HasSuffix(ft.logs[0], "level=INFO msg=info caller="+l.Entries()[0].Caller+" a=1\n") {
+		t.Errorf("Dump(): got %q", ft.logs)
+	}
+}